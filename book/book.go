@@ -0,0 +1,235 @@
+package book
+
+// opening book: scores and best moves of the first plies, from deep offline analysis, v. BOOK
+//
+// File format, little endian:
+//   - magic "BOOK1", number of entries (4 bytes)
+//   - for each entry, sorted by rank: rank (8 bytes), low, high, verdict, move, depth (1 byte each)
+//
+// Ranks and scores are from the perspective of the side to move, as in the transposition table;
+// scores exclude the captures so far.
+// Entries of the successors of searched positions have no move of their own.
+// The scores stem from the book game's history: cycles reached by another move order are not taken into account.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"sankofa/mech"
+	"sankofa/ow"
+	"sort"
+	"strconv"
+)
+
+// book file
+var FileName string
+
+// file header
+const MAGIC = "BOOK1"
+
+// entry without a best move
+const NO_MOVE = int8(-1)
+
+// bytes per entry in the file
+const ENTRY_BYTES = 8 + 5
+
+func init() {
+	// default path to the book file, next to the database
+	user, err := user.Current()
+	ow.Check(err)
+	FileName = path.Clean(path.Join(user.HomeDir, "oware.book"))
+}
+
+// score range and best move of a position
+type Entry struct {
+	Rank      int64
+	Low, High int8 // score interval
+	Verdict   int8
+	Move      int8 // best move; NO_MOVE if only the score is known
+	Depth     int8 // search depth
+}
+
+// entries sorted by rank
+type Book struct {
+	entries []Entry
+}
+
+func (entry *Entry) String() string {
+	move := "-"
+	if entry.Move != NO_MOVE {
+		move = mech.MoveToString(entry.Move)
+	}
+	score := ow.Thousands(entry.Low)
+	if entry.Low != entry.High {
+		score = ow.Thousands(entry.Low, entry.High)
+	}
+	return ow.Thousands(entry.Rank) + ": " + mech.VerdictToString(entry.Verdict) + score + " " + move + " @" + ow.Thousands(entry.Depth)
+}
+
+func New() *Book {
+	return new(Book)
+}
+
+func (book *Book) String() string {
+	return strconv.Itoa(len(book.entries)) + " entries"
+}
+
+// number of entries
+func (book *Book) Len() int {
+	return len(book.entries)
+}
+
+// entry for a rank; false if the position is not in the book
+func (book *Book) Lookup(rank int64) (*Entry, bool) {
+	i := sort.Search(len(book.entries), func(i int) bool {
+		return book.entries[i].Rank >= rank
+	})
+	if i < len(book.entries) && book.entries[i].Rank == rank {
+		r := book.entries[i]
+		return &r, true
+	}
+	return nil, false
+}
+
+// add or replace an entry; deeper entries and entries with a move prevail
+func (book *Book) Add(entry Entry) {
+	i := sort.Search(len(book.entries), func(i int) bool {
+		return book.entries[i].Rank >= entry.Rank
+	})
+	if i < len(book.entries) && book.entries[i].Rank == entry.Rank {
+		old := book.entries[i]
+		if old.Depth > entry.Depth || (old.Depth == entry.Depth && old.Move != NO_MOVE && entry.Move == NO_MOVE) {
+			ow.Log("keep:", &old, "rather than:", &entry)
+			return
+		}
+		book.entries[i] = entry
+		return
+	}
+
+	book.entries = append(book.entries, Entry{})
+	copy(book.entries[i+1:], book.entries[i:])
+	book.entries[i] = entry
+}
+
+// all entries, sorted by rank
+func (book *Book) Entries() []Entry {
+	r := make([]Entry, len(book.entries))
+	copy(r, book.entries)
+	return r
+}
+
+////////////////////////////////////////////////////////////////
+// PERSISTENCE
+////////////////////////////////////////////////////////////////
+
+// write to file; panics on error
+func (book *Book) Save(fileName string) {
+	file, err := os.Create(fileName)
+	ow.Check(err)
+	defer file.Close()
+	ow.Log("save:", fileName, "entries:", len(book.entries))
+
+	writer := bufio.NewWriter(file)
+	_, err = writer.WriteString(MAGIC)
+	ow.Check(err)
+	ow.Check(binary.Write(writer, binary.LittleEndian, uint32(len(book.entries))))
+	for _, entry := range book.entries {
+		ow.Check(binary.Write(writer, binary.LittleEndian, entry.Rank))
+		ow.Check(binary.Write(writer, binary.LittleEndian, [...]int8{entry.Low, entry.High, entry.Verdict, entry.Move, entry.Depth}))
+	}
+	ow.Check(writer.Flush())
+}
+
+// read from file; false if there is no such file, or if it is corrupt
+func Load(fileName string) (*Book, bool) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		// the book is optional
+		ow.Log("no book:", err)
+		return nil, false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		fmt.Println("corrupt opening book:", fileName, err)
+		return nil, false
+	}
+	entries, err := read(bufio.NewReader(file), info.Size())
+	if err != nil {
+		fmt.Println("corrupt opening book:", fileName, err)
+		return nil, false
+	}
+
+	book := New()
+	book.entries = entries
+	ow.Log("loaded:", fileName, "entries:", len(entries))
+	return book, true
+}
+
+// the entries of a book file of the given size
+func read(reader io.Reader, size int64) ([]Entry, error) {
+	magic := make([]byte, len(MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != MAGIC {
+		return nil, fmt.Errorf("not an opening book")
+	}
+
+	var count uint32
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	// as many entries as the file holds
+	if int64(len(MAGIC))+4+int64(count)*ENTRY_BYTES != size {
+		return nil, fmt.Errorf("entries: %d, bytes: %d", count, size)
+	}
+
+	entries := make([]Entry, count)
+	for i := range entries {
+		var fields [5]int8
+		if err := binary.Read(reader, binary.LittleEndian, &entries[i].Rank); err != nil {
+			return nil, err
+		}
+		if err := binary.Read(reader, binary.LittleEndian, &fields); err != nil {
+			return nil, err
+		}
+		entries[i].Low, entries[i].High, entries[i].Verdict, entries[i].Move, entries[i].Depth =
+			fields[0], fields[1], fields[2], fields[3], fields[4]
+		if i > 0 && entries[i-1].Rank >= entries[i].Rank {
+			return nil, fmt.Errorf("not sorted: entry: %d", i)
+		}
+		if err := check(&entries[i]); err != nil {
+			return nil, fmt.Errorf("entry: %d: %v", i, err)
+		}
+	}
+	return entries, nil
+}
+
+// the fields of an entry are usable: the rank, its score interval, the verdict and a legal move
+func check(entry *Entry) error {
+	if entry.Rank < mech.MINRANK || entry.Rank > mech.MAXRANK {
+		return fmt.Errorf("rank out of range: %d", entry.Rank)
+	}
+	level := ow.Level(entry.Rank)
+	if entry.Low > entry.High || entry.Low < -level || entry.High > level {
+		return fmt.Errorf("score out of range: [%d, %d], level: %d", entry.Low, entry.High, level)
+	}
+	switch entry.Verdict {
+	case mech.OPEN, mech.LOSS, mech.DRAW, mech.WIN:
+	default:
+		return fmt.Errorf("no such verdict: %d", entry.Verdict)
+	}
+	if entry.Move == NO_MOVE {
+		return nil
+	}
+	if _, ok := mech.Unrank(entry.Rank).LegalMoves().Next[entry.Move]; !ok {
+		return fmt.Errorf("illegal move: %d", entry.Move)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package book
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"sankofa/mech"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	book := New()
+	book.Add(Entry{Rank: 30, Low: 1, High: 1, Move: 2, Depth: 8})
+	book.Add(Entry{Rank: 10, Low: -1, High: 3, Move: NO_MOVE, Depth: 4})
+	book.Add(Entry{Rank: 20, Low: 0, High: 0, Move: 5, Depth: 6})
+
+	// shallower, or as deep without a move: kept
+	book.Add(Entry{Rank: 30, Low: 4, High: 4, Move: 1, Depth: 7})
+	book.Add(Entry{Rank: 20, Low: 2, High: 2, Move: NO_MOVE, Depth: 6})
+	// deeper: replaced
+	book.Add(Entry{Rank: 10, Low: 2, High: 2, Move: 0, Depth: 5})
+
+	want := []Entry{
+		{Rank: 10, Low: 2, High: 2, Move: 0, Depth: 5},
+		{Rank: 20, Low: 0, High: 0, Move: 5, Depth: 6},
+		{Rank: 30, Low: 1, High: 1, Move: 2, Depth: 8},
+	}
+	if got := book.Entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("entries: %v; want %v", got, want)
+	}
+	if entry, ok := book.Lookup(20); !ok || *entry != want[1] {
+		t.Errorf("Lookup(20) = %v, %v", entry, ok)
+	}
+	if _, ok := book.Lookup(15); ok {
+		t.Error("Lookup(15): found")
+	}
+}
+
+// BOOK1 files: the book is read back as saved
+func TestRoundTrip(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "test.book")
+	book := New()
+	for rank := int64(1); rank <= 100; rank++ {
+		// a legal move, or none
+		move := NO_MOVE
+		if moves := mech.Unrank(rank * 7919).LegalMoves().Moves; rank%3 != 0 && len(moves) > 0 {
+			move = moves[int(rank)%len(moves)]
+		}
+		book.Add(Entry{Rank: rank * 7919, Low: int8(rank%5) - 2, High: int8(rank % 5), Verdict: int8(rank % 4), Move: move, Depth: int8(rank % 20)})
+	}
+	book.Save(fileName)
+
+	loaded, ok := Load(fileName)
+	if !ok {
+		t.Fatal("not loaded")
+	}
+	if !reflect.DeepEqual(book.Entries(), loaded.Entries()) {
+		t.Errorf("saved %v, loaded %v", book, loaded)
+	}
+
+	if _, ok := Load(path.Join(t.TempDir(), "missing.book")); ok {
+		t.Error("missing file loaded")
+	}
+}
+
+// truncated, foreign and unsorted files are not found
+func TestCorrupt(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "test.book")
+	book := New()
+	for rank := int64(1); rank <= 3; rank++ {
+		book.Add(Entry{Rank: rank, Move: NO_MOVE})
+	}
+	book.Save(fileName)
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the second entry's rank, little endian, made larger than the third's
+	unsorted := append([]byte{}, content...)
+	unsorted[len(MAGIC)+4+ENTRY_BYTES] = 9
+
+	for name, corrupt := range map[string][]byte{
+		"empty":      {},
+		"truncated":  content[:len(content)-1],
+		"extended":   append(append([]byte{}, content...), 0),
+		"foreign":    append([]byte("SCC1\x06"), content[len(MAGIC):]...),
+		"huge count": append([]byte(MAGIC), 0xff, 0xff, 0xff, 0xff),
+		"unsorted":   unsorted,
+	} {
+		if err := os.WriteFile(fileName, corrupt, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := Load(fileName); ok {
+			t.Errorf("%s file loaded", name)
+		}
+	}
+}
+
+// well-formed files with an unusable entry are not found
+func TestInvalidEntry(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "test.book")
+	// a single stone: level 1
+	const rank = 1
+	position := mech.Unrank(rank)
+	empty, legal := NO_MOVE, NO_MOVE
+	for move := mech.SOUTHLEFT; move <= mech.SOUTHRIGHT; move++ {
+		if position.Board[move] == 0 {
+			empty = move
+		} else {
+			legal = move
+		}
+	}
+
+	for name, entry := range map[string]Entry{
+		"valid":           {Rank: rank, Low: -1, High: 1, Verdict: mech.OPEN, Move: legal},
+		"high < low":      {Rank: rank, Low: 1, High: 0, Move: NO_MOVE},
+		"low < -level":    {Rank: rank, Low: -2, High: 0, Move: NO_MOVE},
+		"high > level":    {Rank: rank, Low: 0, High: 2, Move: NO_MOVE},
+		"no such rank":    {Rank: mech.MAXRANK + 1, Move: NO_MOVE},
+		"negative rank":   {Rank: -1, Move: NO_MOVE},
+		"no such verdict": {Rank: rank, Verdict: mech.WIN + 1, Move: NO_MOVE},
+		"empty house":     {Rank: rank, Move: empty},
+		"opponent house":  {Rank: rank, Move: mech.SOUTHRIGHT + 1},
+		"no such house":   {Rank: rank, Move: 12},
+		"negative move":   {Rank: rank, Move: -2},
+	} {
+		book := New()
+		book.Add(entry)
+		book.Save(fileName)
+		if _, ok := Load(fileName); ok != (name == "valid") {
+			t.Errorf("%s entry %+v: loaded: %v", name, entry, ok)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sankofa/db"
+	"sankofa/mech"
+	"sankofa/minimax"
+	"sankofa/ow"
+	"strconv"
+	"strings"
+)
+
+// a benchmark position
+type position struct {
+	name     string
+	category string
+	trail    string
+}
+
+// built-in positions: openings, middlegames and database-level endgames
+var positions = []position{
+	{"initial", OPENING, "/1224204106872"},
+	{"opening-2", OPENING, "/1224204106872/D/d"},
+	{"opening-4", OPENING, "/1224204106872/C/f/F/e"},
+	{"middle-43", MIDDLEGAME, "/389678081305"},
+	{"middle-35", MIDDLEGAME, "/40449128654"},
+	{"middle-30", MIDDLEGAME, "/9502759620"},
+	{"end-24", ENDGAME, "/866213555"},
+	{"end-22", ENDGAME, "/472470907"},
+	{"end-14", ENDGAME, "/6267001"},
+	{"end-10", ENDGAME, "/474911"},
+}
+
+// position categories
+const (
+	OPENING    = "opening"
+	MIDDLEGAME = "middlegame"
+	ENDGAME    = "endgame"
+)
+
+// measurements of a position; a line of the baseline file
+type measure struct {
+	name     string
+	depth    int
+	move     string
+	score    string
+	nodes    int
+	seconds  float64
+	hits     int
+	database int
+	times    []float64 // seconds to reach the depths 1, 2, ...; 0 for a depth not reported
+}
+
+func main() {
+	// proper usage message
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stdout, `BENCH measures whether a change to SANKOFA made the engine faster or stronger.
+* A built-in set of positions is searched one by one: openings, middlegames and database-level endgames (-set).
+* Each search is limited by depth or nodes (-depth, -nodes), with a fresh transposition table and no cache.
+* Reported per position: depth, move, score, nodes, nodes/sec., time-to-depth, TT hit rate and database scores.
+* Results can be saved as a baseline (-o) and compared with a saved baseline (-b):
+  node counts, time to the deepest common depth, and changed moves or scores.
+* Node counts are reproducible in deterministic mode (-deterministic, -seed), which searches single-threaded.
+Copyright ©2019-2023 Carlo Monte.
+................................................................................`)
+		fmt.Fprintf(os.Stdout, "%s: benchmark the engine\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	// flags
+	set := "all"    // position category
+	baseline := ""  // compare with this file
+	output := ""    // save to this file
+	goroutines := 1 // degree of parallelism
+	var seed int64
+	var limits minimax.Limits
+	limits.Depth = 10
+	flag.StringVar(&baseline, "b", baseline, "baseline file to compare with; empty: none")
+	flag.StringVar(&db.FileName, "d", db.FileName, "database file")
+	flag.BoolVar(&minimax.Deterministic, "deterministic", false, "reproducible node counts: single goroutine, seeded")
+	flag.IntVar(&goroutines, "g", goroutines, "number of parallel Go-routines")
+	flag.IntVar(&minimax.Hash, "hash", minimax.Hash, "megabytes for the transposition tables and caches of a search")
+	flag.StringVar(&output, "o", output, "baseline file to save the results to; empty: none")
+	flag.BoolVar(&minimax.PVS, "pvs", false, "principal variation search (NegaScout)")
+	flag.IntVar(&minimax.Quiescence, "q", minimax.Quiescence, "quiescence: maximum plies of captures and forced feeding beyond the depth; 0: off")
+	flag.StringVar(&minimax.Search, "search", minimax.Search, "search driver: "+minimax.ASPIRATION+"|"+minimax.MTDF+"|"+minimax.LAZYSMP)
+	flag.Int64Var(&seed, "seed", 1, "random seed of the deterministic mode")
+	flag.StringVar(&set, "set", set, "positions: all|"+OPENING+"|"+MIDDLEGAME+"|"+ENDGAME)
+	flag.IntVar(&limits.Depth, "depth", limits.Depth, "search depth per position; 0: none, v. -nodes")
+	flag.IntVar(&limits.Nodes, "nodes", 0, "visited nodes per position; 0: none")
+	flag.BoolVar(&ow.Verbose, "v", false, "be chatty")
+	flag.Parse()
+
+	if limits.Depth <= 0 && limits.Nodes <= 0 {
+		ow.Panic("no search limit: -depth or -nodes")
+	}
+
+	// reproducible searches
+	if minimax.Deterministic {
+		ow.Reseed(seed)
+		fmt.Println("deterministic: seed:", seed)
+	}
+
+	// database scores for the leaves, if available
+	db.Open()
+	defer db.Close()
+
+	var old map[string]*measure
+	if baseline != "" {
+		old = load(baseline)
+		fmt.Println("baseline:", baseline, ow.Thousands(len(old)), "positions")
+	}
+
+	var measures []*measure
+	for _, p := range positions {
+		if set != "all" && set != p.category {
+			continue
+		}
+		fmt.Println("................................................................................")
+		fmt.Println(p.name, p.category, p.trail)
+		measures = append(measures, run(p, goroutines, limits))
+	}
+	if len(measures) == 0 {
+		ow.Panic("no such set:", set)
+	}
+
+	report(measures, old)
+	if output != "" {
+		save(output, measures, limits)
+		fmt.Println("saved to:", output)
+	}
+}
+
+////////////////////////////////////////////////////////////////
+// MEASURE
+////////////////////////////////////////////////////////////////
+
+// search a position, recording the time to each depth
+func run(p position, goroutines int, limits minimax.Limits) *measure {
+	m := &measure{name: p.name}
+	tt := minimax.NewTT(mech.StringToGame(p.trail)).SetProgress(func(info *minimax.SearchInfo) {
+		for len(m.times) < info.Depth {
+			m.times = append(m.times, 0)
+		}
+		if info.Depth > 0 {
+			m.times[info.Depth-1] = info.Elapsed
+		}
+	})
+	result := tt.ExploreLimits(context.Background(), goroutines, limits).Result()
+	fmt.Println("result:", result)
+
+	m.depth = result.Depth
+	m.move = "-"
+	if result.Move != minimax.NO_MOVE {
+		m.move = mech.MoveToString(result.Move)
+	}
+	m.score = "-"
+	if result.Interval != nil {
+		m.score = strings.ReplaceAll(result.Interval.String(), " ", "")
+	}
+	m.nodes, m.seconds, m.hits, m.database = result.Nodes, result.Elapsed, result.Hits, result.Database
+	return m
+}
+
+// nodes per second
+func (m *measure) nps() float64 {
+	if m.seconds <= 0 {
+		return 0
+	}
+	return float64(m.nodes) / m.seconds
+}
+
+// share of the nodes answered by the transposition table
+func (m *measure) hitRate() string {
+	if m.nodes == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(100*float64(m.hits)/float64(m.nodes), 'f', 1, 64) + "%"
+}
+
+// seconds to reach a depth; false if not reported
+func (m *measure) timeTo(depth int) (float64, bool) {
+	if depth < 1 || depth > len(m.times) || m.times[depth-1] <= 0 {
+		return 0, false
+	}
+	return m.times[depth-1], true
+}
+
+////////////////////////////////////////////////////////////////
+// REPORT
+////////////////////////////////////////////////////////////////
+
+// table of the measures, compared with the baseline, if any
+func report(measures []*measure, old map[string]*measure) {
+	fmt.Println("................................................................................")
+	header := fmt.Sprintf("%-10s %5s %4s %-10s %12s %10s %9s %6s %9s", "position", "depth", "move", "score", "nodes", "nodes/sec", "seconds", "TT", "database")
+	if old != nil {
+		header += fmt.Sprintf(" | %8s %13s %s", "nodes", "time-to-depth", "changed")
+	}
+	fmt.Println(header)
+
+	var nodes, oldNodes int
+	var seconds, oldSeconds float64
+	changed := 0
+	for _, m := range measures {
+		line := fmt.Sprintf("%-10s %5d %4s %-10s %12s %10s %9.3f %6s %9s",
+			m.name, m.depth, m.move, m.score, ow.Thousands(m.nodes), ow.Thousands(int(m.nps())), m.seconds, m.hitRate(), ow.Thousands(m.database))
+		nodes += m.nodes
+		seconds += m.seconds
+
+		if o, ok := old[m.name]; ok {
+			line += " | " + fmt.Sprintf("%8s %13s", ratio(float64(m.nodes), float64(o.nodes)), timeToDepth(m, o))
+			if m.move != o.move || m.score != o.score || m.depth != o.depth {
+				line += " " + o.move + " " + o.score + " @" + ow.Thousands(o.depth)
+				changed++
+			}
+			oldNodes += o.nodes
+			oldSeconds += o.seconds
+		} else if old != nil {
+			line += " | not in baseline"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println("................................................................................")
+	sum := &measure{nodes: nodes, seconds: seconds}
+	total := fmt.Sprintf("total: %s nodes in %.3f sec., %s nodes/sec", ow.Thousands(nodes), seconds, ow.Thousands(int(sum.nps())))
+	if oldNodes > 0 {
+		oldSum := &measure{nodes: oldNodes, seconds: oldSeconds}
+		total += fmt.Sprintf(" | baseline: nodes %s, time %s, nodes/sec %s, %d changed",
+			ratio(float64(nodes), float64(oldNodes)), ratio(seconds, oldSeconds),
+			ratio(sum.nps(), oldSum.nps()), changed)
+	}
+	fmt.Println(total)
+}
+
+// relative change, e.g., -12.5%
+func ratio(new, old float64) string {
+	if old <= 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(100*(new-old)/old, 'f', 1, 64) + "%"
+}
+
+// change of the time to the deepest depth reached by both
+func timeToDepth(m, o *measure) string {
+	for depth := ow.Min(len(m.times), len(o.times)); depth >= 1; depth-- {
+		t, ok := m.timeTo(depth)
+		u, okOld := o.timeTo(depth)
+		if ok && okOld {
+			return ratio(t, u) + " @" + ow.Thousands(depth)
+		}
+	}
+	return "-"
+}
+
+////////////////////////////////////////////////////////////////
+// BASELINE
+////////////////////////////////////////////////////////////////
+
+// one line per position: name depth move score nodes seconds hits database times;
+// times are the comma-separated seconds to the depths 1, 2, ..., - if none
+func save(fileName string, measures []*measure, limits minimax.Limits) {
+	file, err := os.Create(fileName)
+	ow.Check(err)
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	fmt.Fprintln(writer, "# limits:", limits.String(), "| search:", minimax.Search, "| pvs:", minimax.PVS, "| quiescence:", minimax.Quiescence)
+	fmt.Fprintln(writer, "# name depth move score nodes seconds hits database times")
+	for _, m := range measures {
+		times := "-"
+		for i, t := range m.times {
+			if i == 0 {
+				times = ""
+			} else {
+				times += ","
+			}
+			times += strconv.FormatFloat(t, 'g', 6, 64)
+		}
+		fmt.Fprintln(writer, m.name, m.depth, m.move, m.score, m.nodes, strconv.FormatFloat(m.seconds, 'g', 6, 64), m.hits, m.database, times)
+	}
+	ow.Check(writer.Flush())
+}
+
+// measures of a baseline file by position name
+func load(fileName string) map[string]*measure {
+	file, err := os.Open(fileName)
+	ow.Check(err)
+	defer file.Close()
+
+	r := make(map[string]*measure)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 9 {
+			ow.Panic("baseline: 9 fields expected:", line)
+		}
+
+		m := &measure{name: fields[0], move: fields[2], score: fields[3]}
+		m.depth = atoi(fields[1])
+		m.nodes = atoi(fields[4])
+		m.seconds = atof(fields[5])
+		m.hits = atoi(fields[6])
+		m.database = atoi(fields[7])
+		if fields[8] != "-" {
+			for _, t := range strings.Split(fields[8], ",") {
+				m.times = append(m.times, atof(t))
+			}
+		}
+		r[m.name] = m
+	}
+	ow.Check(scanner.Err())
+	return r
+}
+
+func atoi(s string) int {
+	n, err := strconv.Atoi(s)
+	ow.Check(err)
+	return n
+}
+
+func atof(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	ow.Check(err)
+	return f
+}
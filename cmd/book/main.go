@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sankofa/book"
+	"sankofa/db"
+	"sankofa/mech"
+	"sankofa/minimax"
+	"sankofa/ow"
+	"strings"
+	"syscall"
+)
+
+func main() {
+	// proper usage message
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stdout, `BOOK builds the opening book of SANKOFA by deep offline analysis.
+* The positions of the first n plies are searched one by one, with generous limits (-t, -depth, -nodes).
+* Without a games file, all positions of the first n plies are searched;
+  with a games file (-games), only the positions of the first n plies of its games.
+* Games file: one REST trail per line, e.g., /1224204106872/D/d/E; empty lines and #-comments are skipped.
+* Each position's score and best move are stored, together with the scores of its successors.
+* An existing book is extended; deeper entries prevail. The book is saved after each position.
+* SANKOFA plays book positions without searching, v. -book.
+* Interrupting keeps the positions searched so far.
+Copyright ©2019-2023 Carlo Monte.
+................................................................................`)
+		fmt.Fprintf(os.Stdout, "%s: build an opening book\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	// flags
+	n := 2          // plies
+	games := ""     // games file
+	goroutines := 5 // degree of parallelism
+	var limits minimax.Limits
+	limits.Duration = 60
+	flag.StringVar(&book.FileName, "o", book.FileName, "book file")
+	flag.StringVar(&db.FileName, "d", db.FileName, "database file")
+	flag.IntVar(&n, "n", n, "plies")
+	flag.StringVar(&games, "games", games, "games file seeding the book; empty: all positions")
+	flag.IntVar(&goroutines, "g", goroutines, "number of parallel Go-routines")
+	flag.IntVar(&minimax.Hash, "hash", minimax.Hash, "megabytes for the transposition tables and caches of a search")
+	flag.StringVar(&minimax.Search, "search", minimax.Search, "search driver: "+minimax.ASPIRATION+"|"+minimax.MTDF+"|"+minimax.LAZYSMP)
+	flag.Float64Var(&limits.Duration, "t", limits.Duration, "seconds per position; 0: none, v. -depth and -nodes")
+	flag.IntVar(&limits.Depth, "depth", 0, "search depth per position; 0: none")
+	flag.IntVar(&limits.Nodes, "nodes", 0, "visited nodes per position; 0: none")
+	flag.BoolVar(&ow.Verbose, "v", false, "be chatty")
+	flag.Parse()
+
+	if limits.Infinite() {
+		ow.Panic("no search limit: -t, -depth or -nodes")
+	}
+
+	// stop on signals; keep what has been searched
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGHUP)
+	defer stop()
+
+	// database scores for the leaves, if available
+	db.Open()
+	defer db.Close()
+
+	// extend an existing book
+	b, ok := book.Load(book.FileName)
+	if !ok {
+		b = book.New()
+	}
+	fmt.Println("book:", book.FileName, b)
+
+	var positions []*mech.Game
+	if games == "" {
+		positions = plies(n)
+	} else {
+		positions = seeded(games, n)
+	}
+	fmt.Println(ow.Thousands(len(positions)), "positions of", n, "plies")
+
+	// the cache speeds up related positions
+	cache := minimax.NewCache()
+	for i, game := range positions {
+		if ctx.Err() != nil {
+			fmt.Println("interrupted:", i, "positions done")
+			break
+		}
+		fmt.Println("................................................................................")
+		fmt.Println(i+1, "/", len(positions), game)
+
+		tt := cache.NewTT(game).ExploreLimits(ctx, goroutines, limits)
+		if add(b, tt) {
+			b.Save(book.FileName)
+		}
+	}
+	fmt.Println("book:", book.FileName, b)
+}
+
+////////////////////////////////////////////////////////////////
+// POSITIONS
+////////////////////////////////////////////////////////////////
+
+// games reaching the distinct positions of the first n plies, breadth first
+func plies(n int) []*mech.Game {
+	game := mech.StringToGame("/" + ow.Thousands(mech.INIRANK))
+	seen := map[int64]bool{game.Current().Rank(): true}
+	r := []*mech.Game{game}
+	for ply, level := 0, r; ply < n; ply++ {
+		var next []*mech.Game
+		for _, g := range level {
+			if g.GameOver() {
+				continue
+			}
+			for _, move := range g.Current().LegalMoves().Moves {
+				successor := g.Move(move)
+				if rank := successor.Current().Rank(); !seen[rank] && !successor.GameOver() {
+					seen[rank] = true
+					next = append(next, successor)
+				}
+			}
+		}
+		r = append(r, next...)
+		level = next
+	}
+	return r
+}
+
+// the distinct positions of the first n plies of the games in a file
+func seeded(fileName string, n int) []*mech.Game {
+	file, err := os.Open(fileName)
+	ow.Check(err)
+	defer file.Close()
+
+	seen := make(map[int64]bool)
+	var r []*mech.Game
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		trail := strings.TrimSpace(line)
+		if trail == "" {
+			continue
+		}
+
+		game := mech.StringToGame(trail)
+		for ply := 0; ply <= n && ply < len(game.Positions); ply++ {
+			// the game up to the ply
+			g := game.Clone()
+			g.Positions = g.Positions[:ply+1]
+			g.Moves = g.Moves[:ply]
+			g.Cursor = ply
+			if rank := g.Current().Rank(); !seen[rank] && !g.GameOver() {
+				seen[rank] = true
+				r = append(r, g)
+			}
+		}
+	}
+	ow.Check(scanner.Err())
+	return r
+}
+
+////////////////////////////////////////////////////////////////
+// ENTRIES
+////////////////////////////////////////////////////////////////
+
+// store the score and best move of the searched position and the scores of its successors;
+// false if there is nothing to store
+func add(b *book.Book, tt *minimax.TT) bool {
+	result := tt.Result()
+	if result.Interval == nil || result.Move == minimax.NO_MOVE || result.Depth < 1 {
+		fmt.Println("no result:", result)
+		return false
+	}
+	fmt.Println("result:", result)
+
+	depth := int8(ow.Min(result.Depth, int(ow.MAXINT8)))
+	rank := result.PV.Current().Rank()
+	b.Add(entry(rank, result.Interval, result.Move, depth))
+	for _, next := range tt.LegalMoves(rank).Next {
+		if interval := tt.Interval(next); interval != nil {
+			b.Add(entry(next, interval, book.NO_MOVE, depth-1))
+		}
+	}
+	return true
+}
+
+func entry(rank int64, interval *minimax.Interval, move, depth int8) book.Entry {
+	return book.Entry{
+		Rank:    rank,
+		Low:     interval.Low(),
+		High:    interval.High(),
+		Verdict: interval.Verdict(),
+		Move:    move,
+		Depth:   depth,
+	}
+}
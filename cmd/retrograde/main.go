@@ -47,7 +47,11 @@ func main() {
 * Awari gives each player half of the seeds on the board (down to ½ a stone); repeated positions have an effective Awari score of 0.
 * Database scores are used in SANKOFA for the leaves of the α—β search tree. The Oware-Awari inaccuracy is thus tolerated.
 * The database is built incrementally, layer for layer, starting with the empty board.
-* Strongly connected components in lower layers discovered using Tarjan's algorithm (single threaded, in-memory).
+* Strongly connected components in lower layers discovered using Tarjan's algorithm (single threaded, non-recursive)
+  or, with -c parallel, a parallel colouring algorithm (-g goroutines, in-memory).
+* Tarjan keeps packed bit maps in memory and spills its index arrays and stacks to disk beyond the memory limit.
+* -c verify runs both SCC algorithms and stops on any difference.
+* The SCCs of each level are saved to a catalogue (-k) that SANKOFA shows for cycling positions.
 * SCC members belong to cycles. Their scores are initialized accordingly.
 * A layer is incrementally processed, until there are no NEW nodes to score.
 * Additional iterations may further improve the score accuracy, but are avoided for performance reasons.
@@ -64,6 +68,8 @@ Copyright ©2019-2023 Carlo Monte.
 	f := int(-1)       // from level
 	t := int(12)       // to level: lowest useable level
 	s := int(12)       // maximum level for SCC initialization
+	m := int(4096)     // megabytes for the in-memory SCC arrays
+	c := "tarjan"      // SCC algorithm
 	var profiling bool // enable profiling
 	//
 	flag.StringVar(&db.FileName, "d", db.FileName, "database file")
@@ -72,8 +78,13 @@ Copyright ©2019-2023 Carlo Monte.
 	flag.IntVar(&f, "f", f, "from level; overrides the saved checkpoint when >0")
 	flag.IntVar(&s, "s", s, "maximum level where to initialize strongly connected component member's scores")
 	flag.IntVar(&t, "t", t, "to level")
+	flag.StringVar(&c, "c", c, "SCC algorithm: tarjan|parallel|verify")
+	flag.StringVar(&scc.Dir, "k", scc.Dir, "directory of the SCC catalogue")
+	flag.IntVar(&m, "m", m, "megabytes of RAM for the SCC bit maps, index arrays and stacks; larger levels spill to disk, their page caches within the same budget")
+	flag.StringVar(&scc.TempDir, "w", scc.TempDir, "work directory for the spilled SCC index arrays and stacks")
 	flag.BoolVar(&ow.Verbose, "v", false, "be chatty")
 	flag.Parse()
+	scc.MemoryLimit = int64(m) << 20
 
 	// open/create DB file
 	db.Open()
@@ -119,9 +130,23 @@ levels:
 		var it int
 
 		if l <= int8(s) {
-			scc := scc.Tarjan(l)
+			var lists [][]int64
+			switch c {
+			case "tarjan":
+				lists = scc.Tarjan(l)
+			case "parallel":
+				lists = scc.Parallel(l, goroutines)
+			case "verify":
+				lists = scc.Verify(l, goroutines)
+			default:
+				ow.Panic("no such SCC algorithm:", c)
+			}
+			catalogue := scc.NewCatalogue(l, lists)
+			catalogue.Save()
+			fmt.Println(len(catalogue.Components), "strongly connected components saved to:", scc.FileName(l))
+
 			cnt := 0
-			for _, rank := range scc {
+			for _, rank := range catalogue.Members() {
 				_, ini := db.GetScore(rank)
 				if ini {
 					ow.Log("skip initialized: rank:", rank)
@@ -6,9 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"sankofa/book"
 	"sankofa/db"
 	"sankofa/html"
+	"sankofa/minimax"
 	"sankofa/ow"
+	"sankofa/scc"
 )
 
 func main() {
@@ -28,14 +31,30 @@ The following variant of the Oware rules apply:
 * When the game ends, each player takes the seeds on her side of the board.
 SANKOFA provides a MiniMax evaluation of Oware positions featuring:
 * iterative deepener
-* transposition table (thread cooperation; killer moves)
-* parallel aspiration on discrete quartiles
-* negamax
+* fixed-size transposition table (thread cooperation; killer moves; -hash)
+* analysis cache shared by successive requests (-cache)
+* parallel aspiration on discrete quartiles, MTD(f) zero-window searches
+  or lazy SMP on a shared transposition table (-search)
+* negamax, optionally with principal variation search (-pvs)
+* multi-PV: exact scores and continuations for the best root moves, ranked (-pv, -margin, ?pv=all&margin=)
+* pluggable bottom-level evaluation: parity, material/mobility or weights from a file (-e, -weights, ?eval=)
 * fail-soft α—β pruning
+* quiescence search on captures and forced feeding beyond the depth (-q, off by default)
+* combined search limits: time, depth, nodes, proven verdict (-t, -depth, -nodes, -verdict; ?time=0&depth=12);
+  minimax, multi-PV, Monte Carlo and proof-number search share the time of a request
+* opening book built by BOOK from deep offline analysis; book positions are not searched (-book)
+* pondering: background analysis of the position and the expected moves after each response, streamed to the page;
+  reloading uses the improved results (-ponder seconds, with -cache); infinite without limits (-t=0; ?time=0)
+* Monte Carlo tree search (UCT) beside minimax: visits, win rates and continuations of the root moves
+  (-engine=mcts, -playouts, -playout, -uct; ?engine=mcts)
+* proof-number search for a win of South or North, shown as a proof tree (-proof; ?prove=S or ?prove=N)
+* search tree recording: browse the nodes of a search, export as JSON or Graphviz DOT (/tree/trail?plies=3&format=json|dot)
+* deterministic mode for regression tests: identical results for node- or depth-limited searches (-deterministic, -seed)
 * simple score heuristic
+* cycle components (strongly connected components) from the RETROGRADE catalogue
 CAVEATS
 * MiniMax adds a heuristic value for the deepest position; the game continuation does not.
-* MiniMax may end early with a saved score from other threads, leading to a truncated game continuation.
+* MiniMax may end early with a saved score from other threads or earlier requests, leading to a truncated game continuation.
 Copyright ©2019-2023 Carlo Monte.
 ................................................................................`)
 		fmt.Fprintf(os.Stdout, "%s: start a Web server that shows an interactive Oware board\n", os.Args[0])
@@ -44,11 +63,36 @@ Copyright ©2019-2023 Carlo Monte.
 
 	// command line
 	var ipPort string
+	var cache bool
+	var evaluator, weights string
+	var seed int64
+	flag.StringVar(&book.FileName, "book", book.FileName, "opening book file, v. BOOK; empty: none")
 	flag.StringVar(&db.FileName, "d", db.FileName, "database file")
+	flag.StringVar(&html.Engine, "engine", html.Engine, "analyser: "+minimax.MINIMAX+"|"+minimax.MCTS+" (minimax with Monte Carlo tree search; ?engine=)")
+	flag.StringVar(&evaluator, "e", minimax.PARITY, "evaluator: "+minimax.PARITY+"|"+minimax.MATERIAL+"|"+minimax.LINEAR+" (v. -weights)")
 	flag.IntVar(&html.Goroutines, "g", 5, "number of parallel Go-routines")
+	flag.IntVar(&minimax.Hash, "hash", minimax.Hash, "megabytes for the transposition tables and caches of a search")
+	flag.StringVar(&scc.Dir, "k", scc.Dir, "directory of the strongly connected component catalogue")
+	flag.BoolVar(&cache, "cache", true, "keep analysis results across requests")
 	flag.StringVar(&ipPort, "i", "localhost:10000", "listen on IP:Port")
-	flag.Float64Var(&html.DurationLimit, "t", 1, "response time  in seconds")
+	flag.IntVar(&html.Margin, "margin", html.Margin, "mark multi-PV moves losing more stones against the best move")
+	flag.IntVar(&minimax.Quiescence, "q", minimax.Quiescence, "quiescence: maximum plies of captures and forced feeding beyond the depth; 0: off")
+	flag.IntVar(&html.MultiPV, "pv", html.MultiPV, "multi-PV: exact scores for the best n root moves; -1: all, 0: none")
+	flag.Float64Var(&html.Ponder, "ponder", 0, "seconds of background analysis after each response, with -cache; 0: none")
+	flag.StringVar(&minimax.Playout, "playout", minimax.Playout, "MCTS playout policy: "+minimax.RANDOM+"|"+minimax.GREEDY)
+	flag.IntVar(&minimax.Playouts, "playouts", minimax.Playouts, "MCTS playouts unless -nodes is set")
+	flag.IntVar(&minimax.ProofNodes, "proof", minimax.ProofNodes, "node limit of the proof-number search unless -nodes is set (?prove=S|N)")
+	flag.Int64Var(&seed, "seed", 1, "random seed of the deterministic mode")
+	flag.StringVar(&minimax.Search, "search", minimax.Search, "search driver: "+minimax.ASPIRATION+"|"+minimax.MTDF+"|"+minimax.LAZYSMP)
+	flag.BoolVar(&minimax.PVS, "pvs", false, "principal variation search (NegaScout)")
+	flag.Float64Var(&minimax.Exploration, "uct", minimax.Exploration, "MCTS exploration constant")
+	flag.Float64Var(&html.DurationLimit, "t", 1, "response time  in seconds; 0: none, v. -depth and -nodes; without any: respond after 1 sec., then ponder until the next request")
+	flag.IntVar(&html.DepthLimit, "depth", 0, "maximum search depth; 0: none")
+	flag.BoolVar(&minimax.Deterministic, "deterministic", false, "reproducible searches: single goroutine, no cache, seeded board layout; use with -depth or -nodes")
+	flag.IntVar(&html.NodeLimit, "nodes", 0, "maximum number of visited nodes; 0: none")
+	flag.BoolVar(&html.VerdictLimit, "verdict", false, "stop once a win or loss is proven")
 	flag.BoolVar(&ow.Verbose, "v", false, "verbose")
+	flag.StringVar(&weights, "weights", "", "weights file of the "+minimax.LINEAR+" evaluator")
 	flag.Parse()
 
 	// informative output
@@ -58,8 +102,48 @@ Copyright ©2019-2023 Carlo Monte.
 	// open/create DB file
 	db.Open()
 
+	// default evaluator; others can be selected by the URL query
+	if weights != "" {
+		minimax.Register(minimax.ReadWeights(weights))
+	}
+	if e, ok := minimax.Evaluation(evaluator); ok {
+		minimax.Evaluate = e
+		html.Evaluator = e
+	} else {
+		ow.Panic("no such evaluator:", evaluator)
+	}
+
+	// analyser and playout policy
+	if html.Engine != minimax.MINIMAX && html.Engine != minimax.MCTS {
+		ow.Panic("no such engine:", html.Engine)
+	}
+	if minimax.Playout != minimax.RANDOM && minimax.Playout != minimax.GREEDY {
+		ow.Panic("no such playout policy:", minimax.Playout)
+	}
+
+	// reproducible searches and board layouts
+	if minimax.Deterministic {
+		ow.Reseed(seed)
+		fmt.Println("deterministic: seed:", seed)
+	}
+
+	// opening book, if available
+	if book.FileName != "" {
+		if b, ok := book.Load(book.FileName); ok {
+			minimax.Book = b
+			fmt.Println("opening book:", book.FileName, b)
+		}
+	}
+
+	// share results across requests
+	if cache {
+		html.Cache = minimax.NewCache()
+	}
+
 	// start web server
 	http.HandleFunc("/", html.PlayHandler)
+	http.HandleFunc("/ponder/", html.PonderHandler)
+	http.HandleFunc("/tree/", html.TreeHandler)
 	ow.Log("starting web server on:", ipPort)
 	fmt.Println("point your browser to:", "http://"+ipPort)
 
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sankofa/db"
+	"sankofa/mech"
+	"sankofa/minimax"
+	"sankofa/ow"
+	"strconv"
+)
+
+// a database position: features and exact score
+type sample struct {
+	features [minimax.FEATURE_COUNT]float64
+	score    float64
+	stones   float64
+}
+
+func main() {
+	// proper usage message
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stdout, `TUNE fits the weights of the linear evaluator to the RETROGRADE database.
+* Positions are sampled at random from the finished levels, i.e., below the database checkpoint.
+* Their features are those of the SANKOFA material/mobility evaluator; their scores are exact.
+* Least squares yield the initial weights.
+* Texel-style local search then minimises the mean squared error of the rounded and clamped evaluation.
+* The weights file is loaded by SANKOFA with -e=linear -weights=file.
+* The database scores use the Awari rules for cycles, v. RETROGRADE.
+Copyright ©2019-2023 Carlo Monte.
+................................................................................`)
+		fmt.Fprintf(os.Stdout, "%s: tune evaluation weights\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	// flags
+	f := int(1)        // from level
+	t := int(46)       // to level, limited by the checkpoint
+	n := int(100000)   // samples
+	i := int(100)      // local search iterations
+	o := "weights.txt" // output file
+	seed := int64(0)   // random seed
+	flag.StringVar(&db.FileName, "d", db.FileName, "database file")
+	flag.IntVar(&f, "f", f, "from level")
+	flag.IntVar(&t, "t", t, "to level; at most the last finished level")
+	flag.IntVar(&n, "n", n, "number of sampled positions")
+	flag.IntVar(&i, "i", i, "local search iterations; 0: least squares only")
+	flag.StringVar(&o, "o", o, "weights file")
+	flag.Int64Var(&seed, "s", seed, "random seed; 0: time-based")
+	flag.BoolVar(&ow.Verbose, "v", false, "be chatty")
+	flag.Parse()
+
+	if seed != 0 {
+		ow.Reseed(seed)
+	}
+
+	// open DB file; do not create an empty one
+	_, err := os.Stat(db.FileName)
+	ow.Check(err)
+	db.Open()
+	defer db.Close()
+	if !db.Opened() {
+		ow.Panic("no database:", db.FileName)
+	}
+
+	// finished levels only
+	checkpoint := db.GetState()
+	fromLevel := int8(ow.Max(f, 1))
+	toLevel := ow.Min(int8(t), checkpoint-1)
+	if toLevel == 47 {
+		toLevel = 46
+	}
+	if toLevel < fromLevel {
+		ow.Panic("no finished levels: from:", fromLevel, "to:", toLevel, "checkpoint:", checkpoint)
+	}
+	fmt.Println("levels: from:", fromLevel, "to:", toLevel, "checkpoint:", checkpoint)
+
+	samples := sampleLevels(fromLevel, toLevel, n)
+	fmt.Println(ow.Thousands(len(samples)), "positions sampled")
+	if len(samples) == 0 {
+		ow.Panic("no scored positions")
+	}
+
+	// references
+	for _, name := range []string{minimax.PARITY, minimax.MATERIAL} {
+		evaluator, _ := minimax.Evaluation(name)
+		fmt.Println(name, "| mse:", strconv.FormatFloat(mseOf(samples, evaluator), 'f', 4, 64))
+	}
+
+	// fit
+	linear := minimax.NewLinear(leastSquares(samples))
+	fmt.Println("least squares:", linear.Weights, "| mse:", strconv.FormatFloat(mse(samples, linear.Weights), 'f', 4, 64))
+	linear.Weights = localSearch(samples, linear.Weights, i)
+	e := mse(samples, linear.Weights)
+	fmt.Println("local search:", linear.Weights, "| mse:", strconv.FormatFloat(e, 'f', 4, 64))
+
+	// save
+	linear.WriteWeights(o, fmt.Sprintf("tuned on %v positions of levels %v-%v: mse %.4f", len(samples), fromLevel, toLevel, e))
+	fmt.Println("weights saved to:", o)
+}
+
+////////////////////////////////////////////////////////////////
+// SAMPLES
+////////////////////////////////////////////////////////////////
+
+// about n positions with a score, uniformly distributed over the ranks of the levels
+func sampleLevels(fromLevel, toLevel int8, n int) []sample {
+	lowest := ow.LevelUpperLimits[fromLevel-1] + 1
+	highest := ow.LevelUpperLimits[toLevel]
+
+	samples := make([]sample, 0, n)
+	// unscored (unreachable) ranks are skipped; give up after so many attempts
+	for attempt := 0; len(samples) < n && attempt < 10*n; attempt++ {
+		rank := lowest + ow.Rng.Int63n(highest-lowest+1)
+		if !db.Covers(rank) {
+			continue
+		}
+		score, ok := db.GetScore(rank)
+		if !ok {
+			ow.Log("unscored:", rank)
+			continue
+		}
+
+		game := gameAt(rank)
+		samples = append(samples, sample{
+			features: minimax.Features(game),
+			score:    float64(score),
+			stones:   float64(game.Current().Stones()),
+		})
+	}
+	return samples
+}
+
+// game starting at the position with the given rank
+func gameAt(rank int64) *mech.Game {
+	game := mech.NewGame()
+	game.Positions = append(game.Positions, mech.Unrank(rank))
+	return game
+}
+
+////////////////////////////////////////////////////////////////
+// FITTING
+////////////////////////////////////////////////////////////////
+
+// evaluation as done by minimax.Linear: rounded and clamped
+func evaluate(s *sample, weights [minimax.FEATURE_COUNT]float64) float64 {
+	var sum float64
+	for i, feature := range s.features {
+		sum += weights[i] * feature
+	}
+	return math.Round(math.Max(-s.stones, math.Min(s.stones, sum)))
+}
+
+// mean squared error of the linear evaluation
+func mse(samples []sample, weights [minimax.FEATURE_COUNT]float64) float64 {
+	var sum float64
+	for i := range samples {
+		δ := evaluate(&samples[i], weights) - samples[i].score
+		sum += δ * δ
+	}
+	return sum / float64(len(samples))
+}
+
+// mean squared error of any evaluator
+func mseOf(samples []sample, evaluator minimax.Evaluator) float64 {
+	// features suffice for the linear evaluators
+	if linear, ok := evaluator.(*minimax.Linear); ok {
+		return mse(samples, linear.Weights)
+	}
+	// parity is the first feature
+	var weights [minimax.FEATURE_COUNT]float64
+	weights[0] = 1
+	return mse(samples, weights)
+}
+
+// unrounded least squares: solve the normal equations XᵀX w = Xᵀy by Gaussian elimination
+func leastSquares(samples []sample) [minimax.FEATURE_COUNT]float64 {
+	const N = minimax.FEATURE_COUNT
+	var a [N][N + 1]float64
+	for _, s := range samples {
+		for i := 0; i < N; i++ {
+			for j := 0; j < N; j++ {
+				a[i][j] += s.features[i] * s.features[j]
+			}
+			a[i][N] += s.features[i] * s.score
+		}
+	}
+
+	var weights [N]float64
+	for i := 0; i < N; i++ {
+		// partial pivoting
+		pivot := i
+		for j := i + 1; j < N; j++ {
+			if math.Abs(a[j][i]) > math.Abs(a[pivot][i]) {
+				pivot = j
+			}
+		}
+		a[i], a[pivot] = a[pivot], a[i]
+		if math.Abs(a[i][i]) < 1e-9 {
+			// constant feature, e.g., parity on even levels only
+			ow.Log("singular feature:", minimax.FEATURES[i])
+			continue
+		}
+		for j := 0; j < N; j++ {
+			if j != i {
+				factor := a[j][i] / a[i][i]
+				for k := i; k <= N; k++ {
+					a[j][k] -= factor * a[i][k]
+				}
+			}
+		}
+	}
+	for i := 0; i < N; i++ {
+		if math.Abs(a[i][i]) >= 1e-9 {
+			weights[i] = a[i][N] / a[i][i]
+		}
+	}
+	return weights
+}
+
+// Texel tuning: change one weight at a time while the error decreases; halve the step when stuck
+func localSearch(samples []sample, weights [minimax.FEATURE_COUNT]float64, iterations int) [minimax.FEATURE_COUNT]float64 {
+	best := mse(samples, weights)
+	step := 0.1
+	for it := 0; it < iterations && step > 1e-4; it++ {
+		improved := false
+		for i := range weights {
+			for _, δ := range []float64{step, -step} {
+				candidate := weights
+				candidate[i] += δ
+				if e := mse(samples, candidate); e < best {
+					best, weights, improved = e, candidate, true
+					break
+				}
+			}
+		}
+		ow.Log("iteration:", it, "step:", step, "mse:", best, weights)
+		if !improved {
+			step /= 2
+		}
+	}
+	return weights
+}
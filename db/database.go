@@ -145,6 +145,13 @@ func GetState() int8 {
 	return int8(r[0])
 }
 
+// does the database hold scores for the level of the rank? not for ±47 stones,
+// which no capture of 2 or 3 stones leaves on the board; GetScore() and SetScore() panic on them
+func Covers(rank int64) bool {
+	level := ow.Level(rank)
+	return level != -47 && level != 47
+}
+
 // the initial value (zero) maps to -47 and means unreachable
 func SetScore(rank int64, score int8) {
 	if !isOpen {
@@ -152,7 +159,7 @@ func SetScore(rank int64, score int8) {
 	}
 
 	level := ow.Level(rank)
-	if level == -47 || level == 47 {
+	if !Covers(rank) {
 		ow.Panic("level out of range:", level)
 	}
 	// skip unused level-47
@@ -184,9 +191,8 @@ func SetScore(rank int64, score int8) {
 
 // returns the score and true if initialized
 func GetScore(rank int64) (int8, bool) {
-	level := ow.Level(rank)
-	if level == -47 || level == 47 {
-		ow.Panic("level out of range:", level)
+	if !Covers(rank) {
+		ow.Panic("level out of range:", ow.Level(rank))
 	}
 
 	if !isOpen {
@@ -3,10 +3,12 @@ package html
 // compute a position's parameters which are then needed by the web front-end
 
 import (
+	"context"
 	"fmt"
 	"sankofa/mech"
 	"sankofa/minimax"
 	"sankofa/ow"
+	"sankofa/scc"
 )
 
 ////////////////////////////////////////////////////////////////
@@ -16,9 +18,16 @@ import (
 // duration limit for the iterative deepener
 var DurationLimit = float64(1)
 
+// further limits of the iterative deepener, v. minimax.Limits; 0 or false for none
+var DepthLimit, NodeLimit int
+var VerdictLimit bool
+
 // degree of parallelism
 var Goroutines = 5
 
+// results shared by successive requests; nil: each analysis starts afresh
+var Cache *minimax.Cache
+
 ////////////////////////////////////////////////////////////////
 // TYPES
 ////////////////////////////////////////////////////////////////
@@ -35,7 +44,8 @@ type Position struct {
 	// scored: has a valid score?
 	// attack: this moves captures stones
 	// check: this field is attacked
-	movable, scored, attack, check bool
+	// book: the opening book's move
+	movable, scored, attack, check, book bool
 }
 
 type Game struct {
@@ -46,6 +56,16 @@ type Game struct {
 	moves [12]*Position
 	// transposition table
 	tt *minimax.TT
+	// summary of the search
+	result *minimax.SearchResult
+	// cycle component of the current position; nil if none
+	component *scc.Component
+	// multi-PV: exact root moves, best first; nil if not requested
+	lines []*minimax.Line
+	// proof-number search; nil if not requested
+	proof *minimax.Proof
+	// Monte Carlo tree search; nil if not requested
+	mcts *minimax.MCTSResult
 }
 
 ////////////////////////////////////////////////////////////////
@@ -63,11 +83,13 @@ func (position *Position) String() string {
 	r += "movable: " + ow.YesNo(position.movable) +
 		", changed: " + ow.Thousands(position.changed) +
 		", attack: " + ow.YesNo(position.attack) +
-		", check: " + ow.YesNo(position.check)
+		", check: " + ow.YesNo(position.check) +
+		", book: " + ow.YesNo(position.book)
 	return r
 }
 
-func Analysis(trail string) *Game {
+// analysis of the game described by the trail; ends early when the context is cancelled
+func Analysis(ctx context.Context, trail string, options *Options) *Game {
 	////////////////////////////////////////////////////////////////
 	// PREPARE DATA STRUCTURES
 	////////////////////////////////////////////////////////////////
@@ -109,12 +131,32 @@ func Analysis(trail string) *Game {
 	game.previous.rank = game.previous.position.Rank()
 	game.north.rank = game.north.position.Rank()
 
+	// positions are ranked from the perspective of the side to move, as in the catalogue
+	game.component = scc.Find(game.game.Current().Rank())
+
 	////////////////////////////////////////////////////////////////
 	// SERIOUS WORK
 	////////////////////////////////////////////////////////////////
 
-	game.tt = minimax.NewTT(game.game).Explore(Goroutines, DurationLimit)
-	game.game = game.tt.Game()
+	var tt *minimax.TT
+	if Cache != nil {
+		tt = Cache.NewTT(game.game)
+	} else {
+		tt = minimax.NewTT(game.game)
+	}
+	limits := options.share()
+	game.tt = tt.SetEvaluator(options.evaluator).ExploreLimits(ctx, Goroutines, limits)
+	game.result = game.tt.Result()
+	if options.prove != "" {
+		game.proof = minimax.Prove(ctx, game.game, options.prove == "S", limits)
+	}
+	if options.engine == minimax.MCTS {
+		game.mcts = minimax.MonteCarlo(ctx, game.game, Goroutines, limits)
+	}
+	game.game = game.result.PV
+	if options.pv != 0 {
+		game.lines = game.tt.MultiPV(ctx, options.pv, limits)
+	}
 
 	////////////////////////////////////////////////////////////////
 	// PROCESS RESULTS
@@ -192,6 +234,15 @@ func Analysis(trail string) *Game {
 		game.moves[i].changed = ow.Max(0, game.south.position.Board[i]-game.previous.position.Board[i])
 	}
 
+	// opening book move, from the perspective of the side to move
+	if entry := game.tt.BookEntry(); entry != nil {
+		if ow.Even(game.game.Cursor) {
+			game.moves[entry.Move].book = true
+		} else {
+			game.moves[entry.Move+mech.NORTHLEFT].book = true
+		}
+	}
+
 	ow.Log(game)
 
 	fmt.Println(game.tt)
@@ -38,6 +38,9 @@ a.cur {
 a.cont {
 	color: #B8CF5D;
 }
+td.loss, td.loss a:link, td.loss a:visited {
+	color: #E94A5E;
+}
 h1, h2, h3 {
 	background-color: #E8E8E8;
 	color: #39634F;
@@ -9,9 +9,12 @@
 package html
 
 import (
+	"context"
 	"fmt"
 	"sankofa/mech"
+	"sankofa/minimax"
 	"sankofa/ow"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -29,16 +32,26 @@ const INC = "+"
 const INC2 = "⊕" // increment by several stones
 const DEC = "-"
 const DEC2 = "⊖" // decrement by several stones
+const BOOK = "📖" // opening book move
+
+// proof tree nodes shown at most
+const PROOFTREE = 200
 
 // build Web page with GUI for game position and move history
 // WARNING reason for spaghetti: linear story, not much can be reused
-func Display(rest string) string {
-	ow.Log("request:", rest)
+func Display(ctx context.Context, rest string, options *Options) string {
+	ow.Log("request:", rest, options)
 	var html string
 
+	// links keep the options
+	query := options.Query()
+
 	fmt.Println("................................................................................")
 	game := mech.StringToGame(rest)
-	Analysis := Analysis(rest)
+	// links to this position are rebuilt from the game, never copied from the request
+	trail := game.String()
+	Analysis := Analysis(ctx, rest, options)
+	rng := layout(Analysis.game.Current().Rank())
 
 	html += `<!doctype html>
 <html>
@@ -81,6 +94,9 @@ func Display(rest string) string {
 				}
 				html += Analysis.moves[i].αβ
 				html += "|" + ow.Thousands(-Analysis.moves[i].δν)
+				if Analysis.moves[i].book {
+					html += "<span title=\"opening book move\">" + BOOK + "</span>"
+				}
 				// highlight attacks
 				if ow.Odd(Analysis.game.Cursor) && Analysis.moves[i].attack {
 					html += "</th>"
@@ -109,11 +125,11 @@ func Display(rest string) string {
 	// ... moves
 	for i := mech.NORTHRIGHT; i >= mech.NORTHLEFT; i-- {
 		html += "<td title=\"" + mech.MoveToString(i) + " stone counter\">"
-		html += "<a title=\"remove four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -4).Rank()) + "\">" + DEC2 + "</a> "
-		html += "<a title=\"remove one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -1).Rank()) + "\">" + DEC + "</a> "
+		html += "<a title=\"remove four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -4).Rank()) + query + "\">" + DEC2 + "</a> "
+		html += "<a title=\"remove one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -1).Rank()) + query + "\">" + DEC + "</a> "
 		html += ow.Thousands(Analysis.south.position.Board[i]) + " "
-		html += "<a title=\"add one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 1).Rank()) + "\">" + INC + "</a> "
-		html += "<a title=\"add four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 4).Rank()) + "\">" + INC2 + "</a>"
+		html += "<a title=\"add one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 1).Rank()) + query + "\">" + INC + "</a> "
+		html += "<a title=\"add four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 4).Rank()) + query + "\">" + INC2 + "</a>"
 		html += "</td>\n"
 	}
 	html += "</tr>\n"
@@ -147,10 +163,10 @@ func Display(rest string) string {
 		if ow.Odd(Analysis.game.Cursor) && Analysis.moves[i].movable &&
 			!(Analysis.game.Cursor == len(Analysis.game.Positions)-1 && Analysis.game.GameOver()) {
 			html += "<td title=\"play " + mech.MoveToString(i) + "\">\n<a href=\""
-			html += Analysis.game.Move(j).String() + "\">\n"
-			html += SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false) + "</a>\n</td>\n"
+			html += Analysis.game.Move(j).String() + query + "\">\n"
+			html += SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false, rng) + "</a>\n</td>\n"
 		} else {
-			html += "<td>\n" + SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false) + "</td>\n"
+			html += "<td>\n" + SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false, rng) + "</td>\n"
 		}
 	}
 	html += "</tr>\n"
@@ -171,10 +187,10 @@ func Display(rest string) string {
 		if ow.Even(Analysis.game.Cursor) && Analysis.moves[i].movable &&
 			!(Analysis.game.Cursor == len(Analysis.game.Positions)-1 && Analysis.game.GameOver()) {
 			html += "<td title=\"play " + mech.MoveToString(i) + "\">\n<a href=\""
-			html += Analysis.game.Move(i).String() + "\">\n"
-			html += SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false) + "</a>\n</td>\n"
+			html += Analysis.game.Move(i).String() + query + "\">\n"
+			html += SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false, rng) + "</a>\n</td>\n"
 		} else {
-			html += "<td>\n" + SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false) + "</td>\n"
+			html += "<td>\n" + SVG(Analysis.south.position.Board[i], Analysis.moves[i].changed, Analysis.moves[i].check, false, rng) + "</td>\n"
 		}
 	}
 
@@ -205,11 +221,11 @@ func Display(rest string) string {
 	html += "</td>\n"
 	for i := mech.SOUTHLEFT; i <= mech.SOUTHRIGHT; i++ {
 		html += "<td title=\"" + mech.MoveToString(i) + " stone counter\">"
-		html += "<a title=\"remove four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -4).Rank()) + "\">" + DEC2 + "</a> "
-		html += "<a title=\"remove one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -1).Rank()) + "\">" + DEC + "</a> "
+		html += "<a title=\"remove four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -4).Rank()) + query + "\">" + DEC2 + "</a> "
+		html += "<a title=\"remove one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, -1).Rank()) + query + "\">" + DEC + "</a> "
 		html += ow.Thousands(Analysis.south.position.Board[i]) + " "
-		html += "<a title=\"add one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 1).Rank()) + "\">" + INC + "</a> "
-		html += "<a title=\"add four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 4).Rank()) + "\">" + INC2 + "</a>"
+		html += "<a title=\"add one stone\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 1).Rank()) + query + "\">" + INC + "</a> "
+		html += "<a title=\"add four stones\" href =\"/" + ow.Thousands(Analysis.south.position.Edit(i, 4).Rank()) + query + "\">" + INC2 + "</a>"
 		html += "</td>\n"
 	}
 	html += "</tr>\n"
@@ -231,6 +247,9 @@ func Display(rest string) string {
 				}
 				html += Analysis.moves[i].αβ
 				html += "|" + ow.Thousands(-Analysis.moves[i].δν)
+				if Analysis.moves[i].book {
+					html += "<span title=\"opening book move\">" + BOOK + "</span>"
+				}
 
 				// highlight attacks
 				if ow.Even(Analysis.game.Cursor) && Analysis.moves[i].attack {
@@ -256,9 +275,9 @@ func Display(rest string) string {
 	// navigation to initial/rev/final position
 	html += "<table>\n"
 	html += "<tr>\n"
-	html += "<td title=\"game start\"><a href =\"/" + ow.Thousands(mech.INIRANK) + "\">⇐</a></td>\n"
-	html += "<td title=\"reverse the board\"><a href =\"/" + ow.Thousands(Analysis.north.position.Rank()) + "\"> ↺ </a></td>\n"
-	html += "<td title=\"empty board\"><a href =\"/" + ow.Thousands(mech.MINRANK) + "\">⇒</a></td>\n"
+	html += "<td title=\"game start\"><a href =\"/" + ow.Thousands(mech.INIRANK) + query + "\">⇐</a></td>\n"
+	html += "<td title=\"reverse the board\"><a href =\"/" + ow.Thousands(Analysis.north.position.Rank()) + query + "\"> ↺ </a></td>\n"
+	html += "<td title=\"empty board\"><a href =\"/" + ow.Thousands(mech.MINRANK) + query + "\">⇒</a></td>\n"
 	html += "</tr>\n"
 	html += "</table>\n"
 
@@ -266,9 +285,120 @@ func Display(rest string) string {
 	html += "<table>\n"
 	html += "<tr><td>Rank: " + ow.Thousands(Analysis.game.Current().Rank()) + ".</td></tr>\n"
 	html += "<tr><td>" + ow.Thousands(Analysis.south.position.Stones()) + " stones on the board.</td></tr>\n"
-	html += "<tr><td>α—β search depth: " + ow.Thousands(Analysis.tt.Depth()-Analysis.tt.Base()) + ".</td></tr>\n"
+	if Analysis.component != nil {
+		html += "<tr><td title=\"positions that can be repeated without captures; forced repetitions end the game\">"
+		html += "This position is part of cycle component " + Analysis.component.String() + ".</td></tr>\n"
+	}
+	html += "<tr><td>α—β search depth: " + ow.Thousands(Analysis.result.Depth) + ".</td></tr>\n"
+	html += "<tr><td>" + ow.Thousands(Analysis.result.Nodes) + " nodes, " + ow.Thousands(Analysis.result.Database) + " database scores in " +
+		strconv.FormatFloat(Analysis.result.Elapsed, 'f', 2, 64) + " sec.</td></tr>\n"
+	html += "<tr><td title=\"share of the cut-offs by the first searched move; a measure of move ordering\">"
+	html += "First-move cut-offs: " + minimax.FirstMoveRate(Analysis.result.CutOffs, Analysis.result.FirstMove) + ".</td></tr>\n"
+	if entry := Analysis.tt.BookEntry(); entry != nil {
+		html += "<tr><td title=\"deep offline analysis, v. BOOK\">"
+		move := mech.MoveToString(entry.Move)
+		if ow.Odd(Analysis.game.Cursor) {
+			move = strings.ToLower(move)
+		}
+		html += "Opening book " + BOOK + ": " + move + " at depth " + ow.Thousands(entry.Depth) + ".</td></tr>\n"
+	}
+	html += "<tr><td title=\"evaluation of the positions at the bottom of the search; select with ?eval=\">"
+	html += "Evaluator: " + Analysis.tt.Evaluator().String() + ".</td></tr>\n"
+	html += "<tr><td title=\"select with ?time=, ?depth=, ?nodes= and ?verdict=\">"
+	html += "Search limits: " + Analysis.tt.Limits().String() + ".</td></tr>\n"
+	if Pondering(options) && !current(game).GameOver() {
+		html += ponderHTML(trail, query)
+	}
+	html += "<tr><td title=\"Monte Carlo tree search: visits and win rates of the root moves; select with ?engine=\">"
+	html += "Engine: <a href=\"" + trail + options.Engine(minimax.MINIMAX) + "\">" + minimax.MINIMAX + "</a> | "
+	html += "<a href=\"" + trail + options.Engine(minimax.MCTS) + "\">" + minimax.MCTS + "</a>"
+	if Analysis.mcts != nil {
+		html += ": " + Analysis.mcts.String()
+	}
+	html += "</td></tr>\n"
+	html += "<tr><td title=\"the nodes of a single-threaded search: windows, scores, sources and cut-offs; export as JSON or Graphviz DOT\">"
+	html += "Search tree: <a href=\"/tree" + trail + query + "\">" + ow.Thousands(TREE_PLIES) + " plies</a>.</td></tr>\n"
+	html += "<tr><td title=\"proof-number search for the verdict, with the database as terminal knowledge; select with ?prove=\">"
+	html += "Prove: <a href=\"" + trail + options.Prove("S") + "\">♙ wins</a> | "
+	html += "<a href=\"" + trail + options.Prove("N") + "\">♟︎ wins</a>"
+	if Analysis.proof != nil {
+		html += " | <a href=\"" + trail + options.Prove("") + "\">none</a>"
+	}
+	html += ".</td></tr>\n"
 	html += "</table>\n"
 
+	////////////////////////////////////////////////////////////////
+	// PROOF TREE
+	////////////////////////////////////////////////////////////////
+	if Analysis.proof != nil {
+		html += "<p>\n"
+		html += "<table>\n"
+		html += "<tr><th>" + Analysis.proof.String() + "</th></tr>\n"
+		budget := PROOFTREE
+		html += "<tr><td id=\"left\">" + proofTree(Analysis.proof, Analysis.proof.Root, query, &budget) + "</td></tr>\n"
+		html += "</table>\n"
+	}
+
+	////////////////////////////////////////////////////////////////
+	// MULTI-PV
+	////////////////////////////////////////////////////////////////
+	if len(Analysis.lines) > 0 {
+		best := Analysis.lines[0].Score
+		html += "<p>\n"
+		html += "<table>\n"
+		html += "<tr><th>#</th><th>move</th><th title=\"exact score at the search depth\">α—β</th><th>continuation</th></tr>\n"
+		for i, line := range Analysis.lines {
+			// moves losing more than the margin against the best move
+			class := ""
+			title := ""
+			if int(best)-int(line.Score) > options.margin {
+				class = " class=\"loss\""
+				title = " title=\"loses " + ow.Thousands(best-line.Score) + " against the best move\""
+			}
+			clone := line.Game.Clone()
+			clone.Cursor = ow.Min(clone.Cursor+1, len(clone.Positions)-1)
+
+			html += "<tr>"
+			html += "<td" + class + ">" + ow.Thousands(i+1) + ".</td>"
+			html += "<td" + class + title + ">" + moveString(line.Game, line.Game.Cursor+1) + "</td>"
+			html += "<td" + class + ">" + mech.VerdictToString(line.Verdict) + ow.Thousands(line.Score) + "</td>"
+			html += "<td" + class + " id=\"left\"><a href=\"" + clone.String() + query + "\">"
+			for j := line.Game.Cursor + 1; j < len(line.Game.Positions); j++ {
+				html += moveString(line.Game, j) + " "
+			}
+			html += "</a></td>"
+			html += "</tr>\n"
+		}
+		html += "</table>\n"
+	}
+
+	////////////////////////////////////////////////////////////////
+	// MONTE CARLO TREE SEARCH
+	////////////////////////////////////////////////////////////////
+	if Analysis.mcts != nil && len(Analysis.mcts.Lines) > 0 {
+		html += "<p>\n"
+		html += "<table>\n"
+		html += "<tr><th>#</th><th>move</th><th title=\"playouts through the move\">visits</th>"
+		html += "<th title=\"for the side to move; draws count half\">win rate</th><th>most visited continuation</th></tr>\n"
+		for i, line := range Analysis.mcts.Lines {
+			clone := line.Game.Clone()
+			clone.Cursor = ow.Min(clone.Cursor+1, len(clone.Positions)-1)
+
+			html += "<tr>"
+			html += "<td>" + ow.Thousands(i+1) + ".</td>"
+			html += "<td>" + moveString(line.Game, line.Game.Cursor+1) + "</td>"
+			html += "<td>" + ow.Thousands(line.Visits) + "</td>"
+			html += "<td>" + minimax.Percent(line.WinRate) + "</td>"
+			html += "<td id=\"left\"><a href=\"" + clone.String() + query + "\">"
+			for j := line.Game.Cursor + 1; j < len(line.Game.Positions); j++ {
+				html += moveString(line.Game, j) + " "
+			}
+			html += "</a></td>"
+			html += "</tr>\n"
+		}
+		html += "</table>\n"
+	}
+
 	////////////////////////////////////////////////////////////////
 	// GAME HISTORY
 	////////////////////////////////////////////////////////////////
@@ -301,10 +431,7 @@ func Display(rest string) string {
 		}
 
 		// precompute move
-		move := strings.ToLower(mech.MoveToString(clone.Moves[i-1]))
-		if ow.Odd(i) {
-			move = strings.ToUpper(move)
-		}
+		move := moveString(clone, i)
 		// show captures
 		delta := position.Scores[1] - clone.Positions[i-1].Scores[0]
 		if delta > 0 {
@@ -320,7 +447,7 @@ func Display(rest string) string {
 		html += "<td title=\"play " + ow.Thousands((i+1)/2) + ". " + move + "\" id=\"left\">\n"
 
 		clone.Cursor = i
-		html += "<a href =\"" + clone.String() + "\""
+		html += "<a href =\"" + clone.String() + query + "\""
 
 		if i == game.Cursor {
 			html += " class=\"cur\""
@@ -406,3 +533,42 @@ Copyright ©2019-2023 Carlo Monte.
 	fmt.Printf("%.2f seconds\n", float64(time.Now().UTC().UnixNano()-Analysis.tt.Begin())/ow.GIGA64F)
 	return html
 }
+
+// the move leading to the i-th position; upper case for South, lower case for North
+func moveString(game *mech.Game, i int) string {
+	move := strings.ToLower(mech.MoveToString(game.Moves[i-1]))
+	if ow.Odd(i) {
+		move = strings.ToUpper(move)
+	}
+	return move
+}
+
+// nested list of the proof or disproof tree, or of the search tree while unproven;
+// at most budget nodes
+func proofTree(proof *minimax.Proof, node *minimax.ProofNode, query string, budget *int) string {
+	children := node.Proof()
+	if len(children) == 0 {
+		return ""
+	}
+
+	html := "<ul>\n"
+	for _, child := range children {
+		if *budget <= 0 {
+			html += "<li>…</li>\n"
+			break
+		}
+		*budget--
+
+		game := proof.GameOf(child)
+		html += "<li><a href=\"" + game.String() + query + "\">" + moveString(game, game.Cursor) + "</a> "
+		html += minimax.StatusToString(child.Status())
+		if child.Reason != "" {
+			html += " (" + child.Reason + ")"
+		}
+		html += " <small>pn: " + minimax.PNString(child.PN) + ", dn: " + minimax.PNString(child.DN) + "</small>"
+		html += proofTree(proof, child, query, budget)
+		html += "</li>\n"
+	}
+	html += "</ul>\n"
+	return html
+}
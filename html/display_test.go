@@ -0,0 +1,21 @@
+package html
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// the links of the page are rebuilt from the game: text after a move is not copied
+func TestDisplayLinks(t *testing.T) {
+	page := Display(context.Background(), INITIAL+"/D\"><b>xyz", NewOptions(url.Values{"depth": {"1"}}))
+	if strings.Contains(page, "<b>") || strings.Contains(page, "xyz") {
+		t.Error("request text in the page")
+	}
+	for _, link := range []string{"/tree" + INITIAL + "/D(0-0)?", INITIAL + "/D(0-0)?depth=1&engine=", INITIAL + "/D(0-0)?depth=1&prove=S"} {
+		if !strings.Contains(page, "href=\""+link) {
+			t.Errorf("no link: %s", link)
+		}
+	}
+}
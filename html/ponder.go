@@ -0,0 +1,268 @@
+package html
+
+// background analysis (pondering): after responding, the server keeps deepening the position the user studies
+//
+//   - one pondering at a time: the next request stops it and waits for it to end, so that they do not share the CPU
+//   - the position first, then the positions after the expected moves, each with half the remaining time
+//   - the results go to the shared cache: reloading the page, or playing on, starts from them; no pondering without it
+//   - the completed iterations of the position are streamed to /ponder/<trail> as server-sent events
+//
+// Pondering ignores the depth and node limits of the request: only Ponder seconds limit it.
+// A request without limits, e.g., ?time=0, is pondered without them: the position until the next request stops it.
+// It is off in deterministic mode, which does not consult the cache.
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sankofa/mech"
+	"sankofa/minimax"
+	"sankofa/ow"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// seconds of background analysis after each response; 0: none
+var Ponder float64
+
+// expected moves pondered after the position
+const PONDER_PLIES = 2
+
+// the background analysis in progress
+type ponderer struct {
+	mutex       sync.Mutex
+	cancel      context.CancelFunc // nil if idle
+	done        chan struct{}      // closed when the goroutine ends; nil if idle
+	rank        int64              // position streamed
+	last        string             // last event
+	subscribers map[chan string]bool
+}
+
+var pondering = ponderer{subscribers: make(map[chan string]bool)}
+
+// is background analysis possible for the request's options?
+func Pondering(options *Options) bool {
+	return (Ponder > 0 || options.limits.Infinite()) && Cache != nil && !minimax.Deterministic
+}
+
+// stop the background analysis, if any, and wait for it to end; ends the streams
+func StopPondering() {
+	pondering.mutex.Lock()
+	defer pondering.mutex.Unlock()
+
+	pondering._wait()
+}
+
+// cancel the background analysis, if any; returns the channel closed when it has ended, nil if idle
+func (p *ponderer) _stop() chan struct{} {
+	if p.cancel == nil {
+		return nil
+	}
+	ow.Log("stop pondering:", ow.Thousands(p.rank))
+	done := p.done
+	p.cancel()
+	p.cancel = nil
+	p.done = nil
+	p.last = ""
+	for subscriber := range p.subscribers {
+		close(subscriber)
+		delete(p.subscribers, subscriber)
+	}
+	return done
+}
+
+// stop the background analysis and wait for it to end;
+// the mutex is released while waiting, since the goroutine takes it to publish and to end
+func (p *ponderer) _wait() {
+	for p.cancel != nil {
+		done := p._stop()
+		p.mutex.Unlock()
+		<-done
+		p.mutex.Lock()
+	}
+}
+
+// deepen the game's current position and the expected continuation in the background
+func StartPondering(game *mech.Game, options *Options) {
+	game = current(game)
+	if !Pondering(options) || game.GameOver() {
+		return
+	}
+
+	pondering.mutex.Lock()
+	defer pondering.mutex.Unlock()
+
+	pondering._wait()
+	ctx, cancel := context.WithCancel(context.Background())
+	pondering.cancel = cancel
+	pondering.done = make(chan struct{})
+	pondering.rank = game.Current().Rank()
+	go pondering.run(ctx, game, options, pondering.done)
+}
+
+// the position, then the expected moves; closes done when it ends
+func (p *ponderer) run(ctx context.Context, game *mech.Game, options *Options, done chan struct{}) {
+	defer close(done)
+	infinite := options.limits.Infinite()
+	if infinite {
+		fmt.Println("pondering:", game, "until the next request")
+	} else {
+		fmt.Println("pondering:", game, "for", Ponder, "sec.")
+	}
+	remaining := Ponder
+	for ply := 0; ply <= PONDER_PLIES && ctx.Err() == nil && !game.GameOver(); ply++ {
+		limits := minimax.Limits{Duration: remaining / 2, Verdict: options.limits.Verdict}
+		if ply == PONDER_PLIES {
+			limits.Duration = remaining
+		}
+		remaining -= limits.Duration
+		if infinite {
+			// stopped by the next request
+			limits = minimax.Limits{}
+		}
+
+		tt := Cache.NewTT(game).SetEvaluator(options.evaluator)
+		if ply == 0 {
+			tt.SetProgress(func(info *minimax.SearchInfo) {
+				p.publish(ctx, info)
+			})
+		}
+		pv := tt.ExploreLimits(ctx, Goroutines, limits).Result().PV
+		if pv.Cursor+1 >= len(pv.Positions) {
+			break
+		}
+
+		// the expected move
+		game = pv.Clone()
+		game.Cursor++
+		game = current(game)
+	}
+	fmt.Println("pondering done:", game)
+
+	// end the streams, unless stopped already
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if ctx.Err() == nil {
+		p._stop()
+	}
+}
+
+// the game up to its current position
+func current(game *mech.Game) *mech.Game {
+	r := game.Clone()
+	r.Positions = r.Positions[:r.Cursor+1]
+	r.Moves = r.Moves[:r.Cursor]
+	return r
+}
+
+// forward a completed iteration of the position to the streams;
+// dropped if the pondering of ctx was stopped meanwhile, since the streams may belong to the next one
+func (p *ponderer) publish(ctx context.Context, info *minimax.SearchInfo) {
+	move := "none"
+	if info.Move != minimax.NO_MOVE {
+		move = moveString(info.PV, info.PV.Cursor+1)
+	}
+	event := "depth " + ow.Thousands(info.Depth) +
+		": " + move + " " + info.Interval.String() +
+		", " + ow.Thousands(info.Nodes) + " nodes in " + strconv.FormatFloat(info.Elapsed, 'f', 1, 64) + " sec."
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// stopped under the mutex, v. _stop()
+	if ctx.Err() != nil {
+		ow.Log("ponder: stopped: dropped:", event)
+		return
+	}
+	p.last = event
+	for subscriber := range p.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			ow.Log("ponder: stream full: dropped:", event)
+		}
+	}
+}
+
+// stream of the position's events; false if it is not pondered
+func (p *ponderer) subscribe(rank int64) (chan string, string, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.cancel == nil || p.rank != rank {
+		return nil, "", false
+	}
+	subscriber := make(chan string, 8)
+	p.subscribers[subscriber] = true
+	return subscriber, p.last, true
+}
+
+func (p *ponderer) unsubscribe(subscriber chan string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// closed and deleted when stopped
+	if p.subscribers[subscriber] {
+		close(subscriber)
+		delete(p.subscribers, subscriber)
+	}
+}
+
+// callback for web server: server-sent events of the pondered position, e.g., /ponder/1224204106872/A;
+// the event "done" tells the page that the position is no longer pondered
+func PonderHandler(writer http.ResponseWriter, reader *http.Request) {
+	trail := strings.TrimPrefix(reader.URL.Path, "/ponder")
+	if err := mech.CheckGame(trail); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		http.Error(writer, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+
+	subscriber, last, ok := pondering.subscribe(mech.StringToGame(trail).Current().Rank())
+	if !ok {
+		fmt.Fprint(writer, "event: done\ndata: \n\n")
+		flusher.Flush()
+		return
+	}
+	defer pondering.unsubscribe(subscriber)
+	if last != "" {
+		fmt.Fprint(writer, "data: "+last+"\n\n")
+		flusher.Flush()
+	}
+
+	for {
+		select {
+		case <-reader.Context().Done():
+			return
+		case event, ok := <-subscriber:
+			if !ok {
+				fmt.Fprint(writer, "event: done\ndata: \n\n")
+				flusher.Flush()
+				return
+			}
+			fmt.Fprint(writer, "data: "+event+"\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// page element showing the streamed events; trail: the game as regenerated by String()
+func ponderHTML(trail, query string) string {
+	html := "<tr><td title=\"background analysis after the response; the results go to the cache, reload to use them\">"
+	html += "Pondering: <span id=\"ponder\">…</span> <a title=\"reload with the improved results\" href=\"" + trail + query + "\">⟳</a>\n"
+	html += `<script>
+const ponder = new EventSource("/ponder" + location.pathname);
+ponder.onmessage = function(event) { document.getElementById("ponder").textContent = event.data; };
+ponder.addEventListener("done", function() { ponder.close(); document.getElementById("ponder").textContent += " (done)"; });
+</script>
+</td></tr>
+`
+	return html
+}
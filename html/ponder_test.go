@@ -0,0 +1,105 @@
+package html
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sankofa/mech"
+	"sankofa/minimax"
+	"sankofa/ow"
+	"strings"
+	"testing"
+	"time"
+)
+
+// quiet: the logs cost more than the searches
+func TestMain(m *testing.M) {
+	ow.Verbose = false
+	os.Exit(m.Run())
+}
+
+// the initial position
+const INITIAL = "/1224204106872"
+
+// ?time=0 without other limits: pondered until stopped
+func infinite() *Options {
+	return NewOptions(url.Values{"time": {"0"}, "depth": {"0"}, "nodes": {"0"}, "verdict": {"false"}})
+}
+
+// no pondering without the cache, nor without the time or the request to do so
+func TestPonderingOff(t *testing.T) {
+	defer func(ponder float64, cache *minimax.Cache) { Ponder, Cache = ponder, cache }(Ponder, Cache)
+
+	Ponder, Cache = 1, nil
+	if Pondering(infinite()) {
+		t.Error("pondering without a cache")
+	}
+	Ponder, Cache = 0, minimax.NewCache()
+	if Pondering(NewOptions(url.Values{"time": {"1"}})) {
+		t.Error("pondering without time")
+	}
+	if !Pondering(infinite()) {
+		t.Error("no pondering of an infinite request")
+	}
+}
+
+// a started pondering streams its iterations until it is stopped; then the stream ends and nothing is published
+func TestPonderStartStop(t *testing.T) {
+	defer func(ponder float64, cache *minimax.Cache) { Ponder, Cache = ponder, cache }(Ponder, Cache)
+	defer StopPondering()
+	Ponder, Cache = 0, minimax.NewCache()
+
+	game := mech.StringToGame(INITIAL)
+	rank := game.Current().Rank()
+	StartPondering(game, infinite())
+	if _, _, ok := pondering.subscribe(game.Move(mech.A).Current().Rank()); ok {
+		t.Error("another position streamed")
+	}
+	subscriber, _, ok := pondering.subscribe(rank)
+	if !ok {
+		t.Fatal("not pondered")
+	}
+
+	// the iterations of the position
+	select {
+	case event := <-subscriber:
+		if !strings.HasPrefix(event, "depth ") {
+			t.Errorf("event: %q", event)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no event")
+	}
+
+	StopPondering()
+	for range subscriber {
+		// drained until closed by the stop
+	}
+	if _, _, ok := pondering.subscribe(rank); ok {
+		t.Error("stopped pondering streamed")
+	}
+	pondering.mutex.Lock()
+	last, cancel := pondering.last, pondering.cancel
+	pondering.mutex.Unlock()
+	if last != "" || cancel != nil {
+		t.Errorf("stopped: last event: %q, running: %v", last, cancel != nil)
+	}
+}
+
+// the ponder stream: bad trails are bad requests, positions not pondered are done at once
+func TestPonderHandler(t *testing.T) {
+	for trail, want := range map[string]int{
+		"/ponder/x":                http.StatusBadRequest,
+		"/ponder" + INITIAL + "/G": http.StatusBadRequest,
+		"/ponder" + INITIAL:        http.StatusOK,
+	} {
+		recorder := httptest.NewRecorder()
+		PonderHandler(recorder, httptest.NewRequest(http.MethodGet, trail, nil))
+		if recorder.Code != want {
+			t.Errorf("%s: status %d; want %d", trail, recorder.Code, want)
+		}
+		if want == http.StatusOK && !strings.Contains(recorder.Body.String(), "event: done") {
+			t.Errorf("%s: %q; want done", trail, recorder.Body.String())
+		}
+	}
+}
@@ -5,15 +5,199 @@ package html
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
 	"sankofa/mech"
+	"sankofa/minimax"
 	"sankofa/ow"
+	"strconv"
+	"strings"
 )
 
+// evaluator unless selected by the request
+var Evaluator minimax.Evaluator = minimax.Parity{}
+
+// multi-PV lines unless selected by the request: 0 for none, -1 for all moves
+var MultiPV = 0
+
+// mark multi-PV moves that lose more stones than this against the best move
+var Margin = 2
+
+// analyser unless selected by the request: minimax alone, or with Monte Carlo tree search
+var Engine = minimax.MINIMAX
+
+// analysis options from the URL query, e.g., /4-4-4-4-4-4-4-4-4-4-4-4?eval=material&pv=all&margin=1
+// or, for reproducible results, ?time=0&depth=12 or ?time=0&nodes=100000;
+// ?time=0 alone searches infinitely: the response after the default duration, then pondering until the next request;
+// ?prove=S or ?prove=N tries to prove that South or North wins;
+// ?engine=mcts adds the statistics of a Monte Carlo tree search
+type Options struct {
+	engine    string
+	evaluator minimax.Evaluator
+	pv        int
+	margin    int
+	limits    minimax.Limits
+	prove     string // S, N or empty for no proof-number search
+}
+
+// default limits
+func limits() minimax.Limits {
+	return minimax.Limits{Duration: DurationLimit, Depth: DepthLimit, Nodes: NodeLimit, Verdict: VerdictLimit}
+}
+
+// unknown or missing options take the defaults
+func NewOptions(query url.Values) *Options {
+	options := new(Options)
+	options.engine = Engine
+	options.evaluator = Evaluator
+	options.pv = MultiPV
+	options.margin = Margin
+	options.limits = limits()
+
+	switch engine := query.Get("engine"); engine {
+	case "":
+	case minimax.MINIMAX, minimax.MCTS:
+		options.engine = engine
+	default:
+		ow.Log("no such engine:", engine)
+	}
+	if name := query.Get("eval"); name != "" {
+		if evaluator, ok := minimax.Evaluation(name); ok {
+			options.evaluator = evaluator
+		} else {
+			ow.Log("no such evaluator:", name)
+		}
+	}
+	if pv := query.Get("pv"); pv == "all" {
+		options.pv = -1
+	} else if n, err := strconv.Atoi(pv); err == nil {
+		options.pv = ow.Max(-1, n)
+	}
+	if n, err := strconv.Atoi(query.Get("margin")); err == nil {
+		options.margin = ow.Max(0, n)
+	}
+	if t, err := strconv.ParseFloat(query.Get("time"), 64); err == nil {
+		options.limits.Duration = math.Max(0, t)
+	}
+	if n, err := strconv.Atoi(query.Get("depth")); err == nil {
+		options.limits.Depth = ow.Max(0, n)
+	}
+	if n, err := strconv.Atoi(query.Get("nodes")); err == nil {
+		options.limits.Nodes = ow.Max(0, n)
+	}
+	if b, err := strconv.ParseBool(query.Get("verdict")); err == nil {
+		options.limits.Verdict = b
+	}
+	switch side := strings.ToUpper(query.Get("prove")); side {
+	case "", "S", "N":
+		options.prove = side
+	default:
+		ow.Log("no such side:", side)
+	}
+	return options
+}
+
+// limits of the response: those requested, unless they would not end the search;
+// infinite limits are then pondered until the next request, v. Pondering()
+func (options *Options) answer() minimax.Limits {
+	r := options.limits
+	// a web request needs an answer
+	if r.Duration <= 0 && r.Depth <= 0 && r.Nodes <= 0 {
+		ow.Log("no limit: default duration:", DurationLimit)
+		r.Duration = ow.Max(DurationLimit, 1)
+	}
+	return r
+}
+
+func (options *Options) String() string {
+	return "engine: " + options.engine +
+		", evaluator: " + options.evaluator.String() +
+		", multi-PV: " + ow.Thousands(options.pv) +
+		", margin: " + ow.Thousands(options.margin) +
+		", limits: " + options.limits.String() +
+		", prove: " + options.prove
+}
+
+// limits of each engine run for the request: the alpha-beta search and the selected
+// proof-number search, Monte Carlo tree search and multi-PV run one after the other and share the duration
+func (options *Options) share() minimax.Limits {
+	engines := 1
+	if options.prove != "" {
+		engines++
+	}
+	if options.engine == minimax.MCTS {
+		engines++
+	}
+	if options.pv != 0 {
+		engines++
+	}
+
+	r := options.answer()
+	r.Duration /= float64(engines)
+	return r
+}
+
+// URL query to be appended to links; empty for the defaults
+func (options *Options) Query() string {
+	query := url.Values{}
+	if options.engine != Engine {
+		query.Set("engine", options.engine)
+	}
+	if options.evaluator != Evaluator {
+		query.Set("eval", options.evaluator.String())
+	}
+	if options.pv != MultiPV {
+		if options.pv < 0 {
+			query.Set("pv", "all")
+		} else {
+			query.Set("pv", strconv.Itoa(options.pv))
+		}
+	}
+	if options.margin != Margin {
+		query.Set("margin", strconv.Itoa(options.margin))
+	}
+	defaults := limits()
+	if options.limits.Duration != defaults.Duration {
+		query.Set("time", strconv.FormatFloat(options.limits.Duration, 'f', -1, 64))
+	}
+	if options.limits.Depth != defaults.Depth {
+		query.Set("depth", strconv.Itoa(options.limits.Depth))
+	}
+	if options.limits.Nodes != defaults.Nodes {
+		query.Set("nodes", strconv.Itoa(options.limits.Nodes))
+	}
+	if options.limits.Verdict != defaults.Verdict {
+		query.Set("verdict", strconv.FormatBool(options.limits.Verdict))
+	}
+	if options.prove != "" {
+		query.Set("prove", options.prove)
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query.Encode()
+}
+
+// URL query with another proof-number search: S, N or empty for none
+func (options *Options) Prove(side string) string {
+	other := *options
+	other.prove = side
+	return other.Query()
+}
+
+// URL query with another analyser
+func (options *Options) Engine(engine string) string {
+	other := *options
+	other.engine = engine
+	return other.Query()
+}
+
 // callback for web server;
-// incorrect requests are redirected to the initial position
+// the empty path is redirected to the initial position, other incorrect trails are bad requests;
+// the analysis stops when the browser disconnects; pondering follows the response, v. Ponder
 func PlayHandler(writer http.ResponseWriter, reader *http.Request) {
-	rest := reader.URL.String()
+	rest := reader.URL.Path
 
 	if rest == "/favicon.ico" {
 		ow.Log("we don't serve hot icons")
@@ -22,12 +206,25 @@ func PlayHandler(writer http.ResponseWriter, reader *http.Request) {
 	}
 
 	if rest == "/" {
-		initial := "/" + ow.Thousands(mech.INIRANK)
+		initial := "/" + ow.Thousands(mech.INIRANK) + NewOptions(reader.URL.Query()).Query()
 		ow.Log("redirecting empty to:", initial)
 		http.Redirect(writer, reader, initial, 301)
 		return
 	}
 
-	fmt.Fprint(writer, Display(rest))
+	if err := mech.CheckGame(rest); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the request takes the CPU
+	StopPondering()
+	options := NewOptions(reader.URL.Query())
+	html := Display(reader.Context(), rest, options)
+	// before the page subscribes to the stream
+	if reader.Context().Err() == nil {
+		StartPondering(mech.StringToGame(rest), options)
+	}
+	fmt.Fprint(writer, html)
 	return
 }
@@ -0,0 +1,24 @@
+package html
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// the play page: bad trails are bad requests, before any search
+func TestPlayHandler(t *testing.T) {
+	for trail, want := range map[string]int{
+		"/":                  http.StatusMovedPermanently,
+		"/x":                 http.StatusBadRequest,
+		INITIAL + "/G":       http.StatusBadRequest,
+		INITIAL + "/D\"><b>": http.StatusBadRequest,
+		INITIAL + "/Dxyz":    http.StatusBadRequest,
+	} {
+		recorder := httptest.NewRecorder()
+		PlayHandler(recorder, httptest.NewRequest(http.MethodGet, trail, nil))
+		if recorder.Code != want {
+			t.Errorf("%s: status %d; want %d", trail, recorder.Code, want)
+		}
+	}
+}
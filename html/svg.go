@@ -5,7 +5,10 @@ package html
 import (
 	"fmt"
 	"math"
+	"math/rand"
+	"sankofa/minimax"
 	"sankofa/ow"
+	"time"
 )
 
 ////////////////////////////////////////////////////////////////
@@ -47,7 +50,7 @@ var combPosition = [][]float64{
 }
 
 // a ring of stones, randomly rotated
-func ring(count, moved int8) string {
+func ring(count, moved int8, rng *rand.Rand) string {
 	ow.Log(count, moved)
 	var r string
 
@@ -62,7 +65,7 @@ func ring(count, moved int8) string {
 		}
 	} else {
 		// random rotation of sone placement
-		Rnd := ow.Rng.Float64() * 2 * math.Pi
+		Rnd := rng.Float64() * 2 * math.Pi
 		for i := ow.ZERO8; i < count; i++ {
 			I := float64(i)
 			φ := math.Pi * (2*I/countFloat + Rnd)
@@ -81,10 +84,10 @@ func ring(count, moved int8) string {
 
 // stones placed on specific positions on a honeybee comb
 // the orientation is somewhat random, for a more natural look
-func comb(count, moved int8) string {
+func comb(count, moved int8, rng *rand.Rand) string {
 	ow.Log(count, moved)
 	var r string
-	Rnd := ow.Rng.Float64() * float64(2) * math.Pi
+	Rnd := rng.Float64() * float64(2) * math.Pi
 	sin, cos := math.Sincos(Rnd)
 	ow.Log(Rnd, sin, cos)
 	for i := ow.ZERO8; i < count; i++ {
@@ -100,11 +103,11 @@ func comb(count, moved int8) string {
 }
 
 // random place stones in huge houses
-func random(count, moved int8) string {
+func random(count, moved int8, rng *rand.Rand) string {
 	ow.Log(count, moved)
 	var r string
 
-	r += comb(randomLimit, randomLimit-(count-moved))
+	r += comb(randomLimit, randomLimit-(count-moved), rng)
 
 	countFloat := float64(count)
 	RingRadius := stoneRadius / math.Sin(math.Pi/randomRing)
@@ -112,8 +115,8 @@ func random(count, moved int8) string {
 	// random rotation of sone placement
 	for i := ow.ZERO8; i < count-randomLimit; i++ {
 		I := float64(i)
-		R := ow.Rng.Float64() * RingRadius
-		φ := math.Pi * (2*I/(countFloat-randomLimit) + ow.Rng.Float64()*2*math.Pi)
+		R := rng.Float64() * RingRadius
+		φ := math.Pi * (2*I/(countFloat-randomLimit) + rng.Float64()*2*math.Pi)
 		X := svgRadius + R*math.Cos(φ)
 		Y := svgRadius + R*math.Sin(φ)
 		if i >= count-moved-randomLimit {
@@ -127,23 +130,32 @@ func random(count, moved int8) string {
 }
 
 // facade for various stone placement functions
-func stones(count, moved int8) string {
+func stones(count, moved int8, rng *rand.Rand) string {
 	var r string
 
 	switch {
 	case count < 6:
-		r += ring(count, moved)
+		r += ring(count, moved, rng)
 	case count <= randomLimit:
-		r += comb(count, moved)
+		r += comb(count, moved, rng)
 	default:
-		r += random(count, moved)
+		r += random(count, moved, rng)
 	}
 
 	return r
 }
 
+// random stone placement of a page; the same for the same position in deterministic mode
+func layout(rank int64) *rand.Rand {
+	seed := time.Now().UTC().UnixNano()
+	if minimax.Deterministic {
+		seed = ow.Seed + rank
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
 // create a string with an SVG diagram of a house
-func SVG(count, moved int8, check, embedCSS bool) string {
+func SVG(count, moved int8, check, embedCSS bool, rng *rand.Rand) string {
 	var r string
 	if embedCSS {
 		r += fmt.Sprintf("<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%f\" height=\"%f\">\n", 2*svgRadius, 2*svgRadius)
@@ -156,7 +168,7 @@ func SVG(count, moved int8, check, embedCSS bool) string {
 	} else {
 		r += fmt.Sprintf("<circle cx=\"%f\" cy=\"%f\" r=\"%f\" id=\"house\"/>\n", svgRadius, svgRadius, houseRadius)
 	}
-	r += stones(count, moved)
+	r += stones(count, moved, rng)
 	r += "</svg>\n"
 	return r
 }
@@ -0,0 +1,151 @@
+package html
+
+// browser of the recorded search tree, e.g., /tree/1224204106872/F?plies=3&depth=6
+//
+//   - the position is searched afresh, single-threaded and without the cache, recording the given plies (TREE_PLIES by default)
+//   - the tree of the last completed iteration is shown as nested, collapsible lists, at most TREE_HTML nodes
+//   - ?format=json and ?format=dot export the whole recorded tree, v. minimax.SearchTree
+//
+// The search options are those of the position's page, v. Options.
+
+import (
+	"fmt"
+	"net/http"
+	"sankofa/mech"
+	"sankofa/minimax"
+	"sankofa/ow"
+	"strconv"
+	"strings"
+)
+
+// recorded plies unless selected by ?plies=
+const TREE_PLIES = 3
+
+// tree nodes shown at most
+const TREE_HTML = 2000
+
+// callback for web server: the recorded search tree of the position
+func TreeHandler(writer http.ResponseWriter, reader *http.Request) {
+	rest := strings.TrimPrefix(reader.URL.Path, "/tree")
+	query := reader.URL.Query()
+	options := NewOptions(query)
+	plies := TREE_PLIES
+	if n, err := strconv.Atoi(query.Get("plies")); err == nil && n > 0 {
+		plies = n
+	}
+
+	if err := mech.CheckGame(rest); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the request takes the CPU
+	StopPondering()
+	game := mech.StringToGame(rest)
+	fmt.Println("tree:", game, "plies:", plies, options)
+	tt := minimax.NewTT(game).SetEvaluator(options.evaluator).SetRecord(plies)
+	tree := tt.ExploreLimits(reader.Context(), 1, options.answer()).Tree()
+	if tree == nil {
+		// book positions are not searched
+		tree = &minimax.SearchTree{Game: game.String(), Plies: plies}
+	}
+
+	switch format := query.Get("format"); format {
+	case "json":
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(tree.JSON())
+	case "dot":
+		writer.Header().Set("Content-Type", "text/vnd.graphviz")
+		fmt.Fprint(writer, tree.DOT())
+	default:
+		fmt.Fprint(writer, treeHTML(tree, options.Query(), plies))
+	}
+}
+
+// URL query of the tree page with the given format
+func treeQuery(query string, plies int, format string) string {
+	extra := "plies=" + strconv.Itoa(plies)
+	if format != "" {
+		extra += "&format=" + format
+	}
+	if query == "" {
+		return "?" + extra
+	}
+	return query + "&" + extra
+}
+
+// page of the recorded tree
+func treeHTML(tree *minimax.SearchTree, query string, plies int) string {
+	html := `<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+`
+	html += CSS()
+	html += "<title>Oware search tree</title>\n"
+	html += "</head>\n"
+	html += "<body>\n"
+	html += "<h3>Search tree</h3>\n"
+
+	html += "<p>\n"
+	html += "<a href=\"" + tree.Game + query + "\">" + tree.Game + "</a>: " + tree.String() + ".<br>\n"
+	html += "Plies: "
+	for _, n := range []int{1, 2, 3, 4, 6} {
+		html += "<a href=\"/tree" + tree.Game + treeQuery(query, n, "") + "\">" + ow.Thousands(n) + "</a> "
+	}
+	html += "| export: <a href=\"/tree" + tree.Game + treeQuery(query, plies, "json") + "\">JSON</a> "
+	html += "<a href=\"/tree" + tree.Game + treeQuery(query, plies, "dot") + "\">DOT</a>.<br>\n"
+	html += "<small>Scores from the perspective of the side to move, against the α—β window; " +
+		"the source of the score, and the cut-off if the node stopped early.</small>\n"
+	html += "</p>\n"
+
+	budget := TREE_HTML
+	html += "<p>\n<ul>\n"
+	for i, root := range tree.Roots {
+		if budget <= 0 {
+			html += "<li>…</li>\n"
+			break
+		}
+		budget--
+		// the last call sets the result
+		open := ""
+		if i == len(tree.Roots)-1 {
+			open = " open"
+		}
+		html += "<li><details" + open + "><summary>call " + ow.Thousands(i+1) + ": " + root.String() + "</summary>\n"
+		html += searchTree(root, query, &budget)
+		html += "</details></li>\n"
+	}
+	html += "</ul>\n</p>\n"
+
+	html += "</body>\n</html>\n"
+	return html
+}
+
+// nested list of the children; at most budget nodes
+func searchTree(node *minimax.TreeNode, query string, budget *int) string {
+	if len(node.Children) == 0 {
+		return ""
+	}
+
+	html := "<ul>\n"
+	for _, child := range node.Children {
+		if *budget <= 0 {
+			html += "<li>…</li>\n"
+			break
+		}
+		*budget--
+
+		label := "<a href=\"" + child.Trail + query + "\">" + child.Move + "</a>" + strings.TrimPrefix(child.String(), child.Move)
+		label += " <small>depth: " + ow.Thousands(child.Depth) + "</small>"
+		if len(child.Children) == 0 {
+			html += "<li>" + label + "</li>\n"
+			continue
+		}
+		html += "<li><details><summary>" + label + "</summary>\n"
+		html += searchTree(child, query, budget)
+		html += "</details></li>\n"
+	}
+	html += "</ul>\n"
+	return html
+}
@@ -22,6 +22,8 @@ package mech
 // representation of an Oware game
 
 import (
+	"fmt"
+	"regexp"
 	"sankofa/ow"
 	"strconv"
 	"strings"
@@ -129,6 +131,46 @@ func StringToGame(rest string) *Game {
 	return game
 }
 
+// a move of the REST format: optional cursor marker, house, optional score, optional game-over mark
+var moveElement = regexp.MustCompile(`^!?[A-Fa-f](\([0-9]+-[0-9]+\))?\.?$`)
+
+// error if a text is not in the format of String(), e.g., a trail sent by a Web client;
+// StringToGame() would fail on it or ignore part of it
+func CheckGame(rest string) error {
+	game := NewGame()
+	for i, elem := range strings.Split(rest, "/") {
+		switch i {
+		case 0:
+			continue
+		case 1:
+			rank, err := strconv.ParseInt(elem, 10, 64)
+			if err != nil {
+				return err
+			}
+			if rank < MINRANK || rank > MAXRANK {
+				return fmt.Errorf("rank out of range: %d", rank)
+			}
+			game.Positions = append(game.Positions, Unrank(rank))
+		default:
+			if game.GameOver() {
+				return fmt.Errorf("move past game-over: %d", i)
+			}
+			if !moveElement.MatchString(elem) {
+				return fmt.Errorf("no such move: %q", elem)
+			}
+			move := StringToMove(strings.ToUpper(strings.TrimPrefix(elem, "!")[:1]))
+			if game.Last().Board[move] == 0 {
+				return fmt.Errorf("empty house: %q", elem)
+			}
+			game = game.Move(move)
+		}
+	}
+	if len(game.Positions) == 0 {
+		return fmt.Errorf("no position: %q", rest)
+	}
+	return nil
+}
+
 // REST format
 func (game *Game) String() string {
 	// sanity check
@@ -277,13 +319,18 @@ func (game *Game) Capture() int8 {
 
 // true if the last position happens more than once
 func (game *Game) Cycle() bool {
-	for i := ow.ZERO64; i < int64(len(game.Positions)-1); i++ {
+	return game.Repetition() >= 0
+}
+
+// index of the earlier position repeated by the last one; -1 if none
+func (game *Game) Repetition() int {
+	for i := 0; i < len(game.Positions)-1; i++ {
 		if game.Last().EQ(game.Positions[i]) {
 			ow.Log("found cycle: @", ow.Thousands(len(game.Positions)-1), "== @", ow.Thousands(i))
-			return true
+			return i
 		}
 	}
-	return false
+	return -1
 }
 
 // game over if the last position's score is final or there is a cycle
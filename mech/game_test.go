@@ -0,0 +1,45 @@
+package mech
+
+import (
+	"os"
+	"sankofa/ow"
+	"strings"
+	"testing"
+)
+
+// quiet: the logs cost more than the tests
+func TestMain(m *testing.M) {
+	ow.Verbose = false
+	os.Exit(m.Run())
+}
+
+// trails of Web clients are checked before StringToGame() exits on them
+func TestCheckGame(t *testing.T) {
+	for _, rest := range []string{"/1224204106872", "/1224204106872/D/d", "/1224204106872/D/!d/F(0-0)", "/40449128654/C/a"} {
+		if err := CheckGame(rest); err != nil {
+			t.Errorf("%s: %v", rest, err)
+		} else if game := StringToGame(rest); game.String() == "" {
+			t.Errorf("%s: no game", rest)
+		}
+	}
+	for _, rest := range []string{"", "/", "/x", "/-1", "/1399358844975", "/1224204106872/", "/1224204106872/G", "/1224204106872/!", "/1224204106872/D/d/D",
+		"/1224204106872/D\"><img src=x onerror=alert(1)>", "/1224204106872/Dxyz", "/1224204106872/D(0-0", "/1224204106872/!!D", "/1224204106872/D.."} {
+		if err := CheckGame(rest); err == nil {
+			t.Errorf("%q: no error", rest)
+		}
+	}
+}
+
+// String() is read back, up to game-over
+func TestCheckString(t *testing.T) {
+	game := StringToGame("/1224204106872")
+	for !game.GameOver() {
+		game = game.Move(game.Last().LegalMoves().Moves[0])
+		if err := CheckGame(game.String()); err != nil {
+			t.Fatalf("%s: %v", game, err)
+		}
+	}
+	if !strings.HasSuffix(game.String(), ".") {
+		t.Errorf("%s: not over", game)
+	}
+}
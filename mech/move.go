@@ -231,6 +231,18 @@ func countMoves(stones, interval int8) (moves, rest int8) {
 	return
 }
 
+// stones sown without reaching to the opponent's side, v. MovesInHand()
+func (position *Position) SeedsInHand() int8 {
+	sih := ow.ZERO8
+	// SOUTHRIGHT cannot possibly be moved without affecting the opponent's board
+	for i := SOUTHLEFT; i < SOUTHRIGHT; i++ {
+		if position.Board[i] <= SOUTHRIGHT-i {
+			sih += position.Board[i]
+		}
+	}
+	return sih
+}
+
 // maximum number of consecutive moves without reaching to the opponent's side
 func (position *Position) MovesInHand() int8 {
 	mih := ow.ZERO8
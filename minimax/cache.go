@@ -0,0 +1,137 @@
+package minimax
+
+// analysis results shared by successive searches, e.g., by the requests of the web server.
+//
+// The intervals of each completed deepener iteration are kept, together with their remaining depth.
+// A later search uses them:
+//   - as scores or bounds for nodes below the first level, if they were searched at least as deep.
+//   - for move ordering, where the previous iteration has no interval.
+//
+// Revisiting a position, or advancing to one of its successors, thus starts from the previous results.
+// The root and its successors are always searched, in order to show their intervals.
+//
+// The results take a quarter of the Hash budget; the memoization caches are those shared by all searches.
+// Each search adds the interval tables of its deepener iterations.
+// Results depend on the evaluator: a search with another evaluator discards them.
+// Results relying on the history of a cycle only apply to the same history, v. history.go.
+
+import (
+	"sankofa/mech"
+	"sankofa/ow"
+	"sync"
+)
+
+// thread-safe store of earlier results, bounded in size
+type Cache struct {
+	results *Table[*Interval]
+
+	// name of the evaluator of the results
+	mutex     sync.RWMutex
+	evaluator string
+}
+
+func NewCache() *Cache {
+	cache := new(Cache)
+	cache.results = NewTable[*Interval]((Hash<<20)/4, INTERVAL_BYTES)
+	cache.evaluator = Evaluate.String()
+	return cache
+}
+
+// transposition table that shares the cache
+func (cache *Cache) NewTT(game *mech.Game) *TT {
+	tt := newTT(game)
+	tt.tt = newIntervals()
+	tt.memo = sharedMemo()
+	tt.cache = cache
+	return tt
+}
+
+func (cache *Cache) String() string {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	return "results: " + cache.results.String() + ", evaluator: " + cache.evaluator
+}
+
+// results of a given evaluator; nil if the cache holds another evaluator's results
+func (cache *Cache) table(evaluator Evaluator) *Table[*Interval] {
+	cache.mutex.RLock()
+	defer cache.mutex.RUnlock()
+
+	if cache.evaluator != evaluator.String() {
+		return nil
+	}
+	return cache.results
+}
+
+// a completed iteration: its intervals go to the cache, if any
+func (tt *TT) keep() {
+	if tt.cache != nil {
+		tt.cache.keep(tt.tt, tt.Evaluator())
+	}
+}
+
+// keep the intervals of a completed iteration; deeper results prevail
+func (cache *Cache) keep(intervals *Table[*Interval], evaluator Evaluator) {
+	cache.mutex.Lock()
+	if cache.evaluator != evaluator.String() {
+		// searches in progress may still read the old results
+		cache.results = NewTable[*Interval]((Hash<<20)/4, INTERVAL_BYTES)
+		cache.evaluator = evaluator.String()
+	}
+	results := cache.results
+	cache.mutex.Unlock()
+
+	intervals.Range(func(rank int64, depth int, interval *Interval) {
+		results.Offer(rank, depth, interval)
+	})
+}
+
+// results of earlier searches with the same evaluator; nil if none or in deterministic mode
+func (tt *TT) results() *Table[*Interval] {
+	if tt.cache == nil || Deterministic {
+		return nil
+	}
+	return tt.cache.table(tt.Evaluator())
+}
+
+// earlier result searched at least as deep, giving a score or a bound outside the α—β window;
+// nil for the root and its successors, for complete searches, without a cache, or for another history
+func (tt *TT) cached(game *mech.Game, rank int64, α, β int8, depth int) (*Interval, int) {
+	if Complete || tt.Depth()-depth <= 1 {
+		return nil, 0
+	}
+	results := tt.results()
+	if results == nil {
+		return nil, 0
+	}
+
+	interval, draft, ok := results.Probe(rank)
+	if !ok || draft < depth {
+		return nil, 0
+	}
+	if _, bound := interval.Bound(α, β); !interval.Scored() && !bound {
+		return nil, 0
+	}
+	if !interval.applies(game) {
+		ow.Log(game, "cache: refused: other history:", interval)
+		tt.incRefused()
+		return nil, 0
+	}
+	return interval, draft
+}
+
+// interval for move ordering: from the previous iteration, else from the cache
+func (tt *TT) hint(rank int64) *Interval {
+	if tt.old != nil {
+		if interval := tt.old.Interval(rank); interval != nil {
+			return interval
+		}
+	}
+	if results := tt.results(); results != nil {
+		if interval, ok := results.Get(rank); ok {
+			return interval
+		}
+	}
+	return nil
+}
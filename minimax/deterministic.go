@@ -0,0 +1,34 @@
+package minimax
+
+// deterministic mode: the same search yields the same result on every run, e.g., for regression tests
+//
+// Sources of variation and their remedies:
+//   - goroutine scheduling: all search drivers run a single goroutine
+//   - wall clock: the duration limit is ignored, unless it is the only limit
+//   - earlier searches: the shared cache is not consulted
+//   - map iteration: legal moves are visited in the order of the sorted moves
+//   - random numbers: seeded, v. ow.Reseed()
+//
+// A search limited by depth or nodes then gives identical scores, continuations and node counts.
+
+import (
+	"sankofa/ow"
+)
+
+// reproducible searches
+var Deterministic bool
+
+// limits without the wall clock in deterministic mode
+func (limits Limits) deterministic() Limits {
+	if !Deterministic || limits.Duration <= 0 {
+		return limits
+	}
+	if limits.Depth <= 0 && limits.Nodes <= 0 {
+		ow.Log("deterministic: duration is the only limit:", limits)
+		return limits
+	}
+
+	ow.Log("deterministic: duration ignored:", limits.Duration)
+	limits.Duration = 0
+	return limits
+}
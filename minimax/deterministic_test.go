@@ -0,0 +1,109 @@
+package minimax
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strings"
+	"testing"
+)
+
+// quiet: the logs cost more than the searches
+func TestMain(m *testing.M) {
+	ow.Verbose = false
+	os.Exit(m.Run())
+}
+
+// an opening, a middlegame and an endgame, as in BENCH
+var deterministicTrails = []string{"/1224204106872/D/d", "/40449128654", "/472470907"}
+
+// the parts of a result that deterministic mode reproduces
+func fingerprint(result *SearchResult) string {
+	r := "depth: " + ow.Thousands(result.Depth) + ", nodes: " + ow.Thousands(result.Nodes)
+	if result.Interval != nil {
+		r += ", score: " + result.Interval.String()
+	}
+	if result.PV != nil {
+		r += ", PV: " + result.PV.String()
+	}
+	return r
+}
+
+func search(trail string, goroutines int, limits Limits) *SearchResult {
+	ow.Reseed(1)
+	return NewTT(mech.StringToGame(trail)).ExploreLimits(context.Background(), goroutines, limits).Result()
+}
+
+// the expected fingerprints, one per line: driver, PVS, limits, trail ⇢ fingerprint
+const GOLDEN = "testdata/deterministic.golden"
+
+// rewrite GOLDEN after an intended change of the engine's results: go test -run Deterministic -update
+var update = flag.Bool("update", false, "rewrite "+GOLDEN)
+
+// depth- and node-limited searches give the scores, continuations and node counts of GOLDEN
+func TestDeterministic(t *testing.T) {
+	defer func(deterministic bool, search string, pvs bool) {
+		Deterministic, Search, PVS = deterministic, search, pvs
+	}(Deterministic, Search, PVS)
+	Deterministic = true
+
+	var got []string
+	for _, driver := range []string{ASPIRATION, MTDF, LAZYSMP} {
+		for _, pvs := range []bool{false, true} {
+			Search, PVS = driver, pvs
+			for _, limits := range []Limits{{Depth: 6}, {Nodes: 20000}, {Duration: 60, Depth: 5}} {
+				for _, trail := range deterministicTrails {
+					// parallelism is requested, deterministic mode ignores it
+					key := fmt.Sprintf("%s, PVS: %v, %v, %s", driver, pvs, limits, trail)
+					got = append(got, key+" ⇢ "+fingerprint(search(trail, 4, limits)))
+				}
+			}
+		}
+	}
+
+	if *update {
+		if err := os.WriteFile(GOLDEN, []byte(strings.Join(got, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	content, err := os.ReadFile(GOLDEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(want) != len(got) {
+		t.Fatalf("%d results; %s has %d", len(got), GOLDEN, len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("\n got: %s\nwant: %s", got[i], want[i])
+		}
+	}
+}
+
+// the duration limit is ignored, unless it is the only one
+func TestDeterministicLimits(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+
+	Deterministic = true
+	for limits, want := range map[Limits]Limits{
+		{Duration: 5, Depth: 8}:  {Depth: 8},
+		{Duration: 5, Nodes: 10}: {Nodes: 10},
+		{Duration: 5}:            {Duration: 5},
+		{Depth: 8}:               {Depth: 8},
+	} {
+		if got := limits.deterministic(); got != want {
+			t.Errorf("%v ⇢ %v; want %v", limits, got, want)
+		}
+	}
+
+	Deterministic = false
+	limits := Limits{Duration: 5, Depth: 8}
+	if got := limits.deterministic(); got != limits {
+		t.Errorf("not deterministic: %v ⇢ %v", limits, got)
+	}
+}
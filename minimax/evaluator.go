@@ -0,0 +1,223 @@
+package minimax
+
+// evaluation of the positions at the bottom of the search, i.e., not found in the database
+//
+// Scores are from the perspective of the side to move and cover only the stones left on the board,
+// as the database scores do; the captures are added up by NegaMax.
+//
+// Evaluators:
+//   - parity: 0 if the stones can be split evenly, -1 otherwise; the original heuristic
+//   - material: fixed weights of the features below
+//   - linear: weights read from a file, e.g., as written by TUNE
+//
+// Features, own minus opponent's:
+//   - parity: as the parity evaluator
+//   - seeds: seeds in hand, i.e., stones in houses sown without reaching the opponent's side
+//   - mobility: moves in hand, i.e., moves not reaching the opponent's side
+//   - vulnerable: houses with 1 or 2 stones, which can be captured when reached
+//
+// Weights file: one "feature weight" pair per line; empty lines and #-comments are skipped.
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////////
+// EVALUATORS
+////////////////////////////////////////////////////////////////
+
+// evaluator names
+const (
+	PARITY   = "parity"
+	MATERIAL = "material"
+	LINEAR   = "linear"
+)
+
+// bottom-level evaluation of the current position of a game
+type Evaluator interface {
+	Evaluate(game *mech.Game) int8
+	String() string
+}
+
+// evaluator of new transposition tables
+var Evaluate Evaluator = Parity{}
+
+// registered evaluators, by name
+var evaluators = map[string]Evaluator{
+	PARITY:   Parity{},
+	MATERIAL: &Linear{name: MATERIAL, Weights: MaterialWeights},
+}
+var evaluatorsMutex sync.RWMutex
+
+// evaluator for a given name; false if there is no such evaluator
+func Evaluation(name string) (Evaluator, bool) {
+	evaluatorsMutex.RLock()
+	defer evaluatorsMutex.RUnlock()
+
+	evaluator, ok := evaluators[name]
+	return evaluator, ok
+}
+
+// make an evaluator selectable by its name
+func Register(evaluator Evaluator) {
+	evaluatorsMutex.Lock()
+	defer evaluatorsMutex.Unlock()
+
+	evaluators[evaluator.String()] = evaluator
+}
+
+////////////////////////////////////////////////////////////////
+// PARITY
+////////////////////////////////////////////////////////////////
+
+// even or odd number of stones
+type Parity struct{}
+
+func (Parity) Evaluate(game *mech.Game) int8 {
+	return game.Heuristic()
+}
+
+func (Parity) String() string {
+	return PARITY
+}
+
+////////////////////////////////////////////////////////////////
+// FEATURES
+////////////////////////////////////////////////////////////////
+
+// feature names, in the order of Features()
+var FEATURES = [...]string{"parity", "seeds", "mobility", "vulnerable"}
+
+const FEATURE_COUNT = len(FEATURES)
+
+// feature vector of the current position of a game
+func Features(game *mech.Game) [FEATURE_COUNT]float64 {
+	position := game.Current()
+	reverse := position.Reverse()
+
+	var vulnerable int
+	for i := mech.SOUTHLEFT; i <= mech.SOUTHRIGHT; i++ {
+		if position.Board[i] == 1 || position.Board[i] == 2 {
+			vulnerable -= 1
+		}
+		if reverse.Board[i] == 1 || reverse.Board[i] == 2 {
+			vulnerable += 1
+		}
+	}
+
+	return [FEATURE_COUNT]float64{
+		float64(game.Heuristic()),
+		float64(position.SeedsInHand()) - float64(reverse.SeedsInHand()),
+		float64(position.MovesInHand()) - float64(reverse.MovesInHand()),
+		float64(vulnerable),
+	}
+}
+
+////////////////////////////////////////////////////////////////
+// LINEAR
+////////////////////////////////////////////////////////////////
+
+// weights of the material/mobility evaluator
+var MaterialWeights = [FEATURE_COUNT]float64{1, 0.25, 0.1, 0.5}
+
+// weighted sum of the features
+type Linear struct {
+	name    string
+	Weights [FEATURE_COUNT]float64
+}
+
+// linear evaluator with given weights; registered under the name LINEAR
+func NewLinear(weights [FEATURE_COUNT]float64) *Linear {
+	return &Linear{name: LINEAR, Weights: weights}
+}
+
+// rounded, within the bounds set by the stones on the board
+func (linear *Linear) Evaluate(game *mech.Game) int8 {
+	features := Features(game)
+	var sum float64
+	for i, feature := range features {
+		sum += linear.Weights[i] * feature
+	}
+
+	stones := float64(game.Current().Stones())
+	return int8(math.Round(math.Max(-stones, math.Min(stones, sum))))
+}
+
+func (linear *Linear) String() string {
+	return linear.name
+}
+
+// read weights from file; unknown features panic, missing ones are 0
+func ReadWeights(fileName string) *Linear {
+	file, err := os.Open(fileName)
+	ow.Check(err)
+	defer file.Close()
+
+	linear, err := readWeights(file)
+	ow.Check(err)
+
+	ow.Log("weights:", fileName, linear.Weights)
+	return linear
+}
+
+// parse the weights file format
+func readWeights(reader io.Reader) (*Linear, error) {
+	linear := NewLinear([FEATURE_COUNT]float64{})
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("weights: feature and weight expected: %q", line)
+		}
+
+		i := featureIndex(fields[0])
+		if i < 0 {
+			return nil, fmt.Errorf("weights: no such feature: %q", fields[0])
+		}
+		weight, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, err
+		}
+		linear.Weights[i] = weight
+	}
+	return linear, scanner.Err()
+}
+
+// write weights to file, in the format read by ReadWeights()
+func (linear *Linear) WriteWeights(fileName string, comment string) {
+	file, err := os.Create(fileName)
+	ow.Check(err)
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	if comment != "" {
+		fmt.Fprintln(writer, "#", comment)
+	}
+	for i, weight := range linear.Weights {
+		fmt.Fprintln(writer, FEATURES[i], strconv.FormatFloat(weight, 'g', -1, 64))
+	}
+	ow.Check(writer.Flush())
+}
+
+// index of a feature in FEATURES; -1 if there is no such feature
+func featureIndex(name string) int {
+	for i, feature := range FEATURES {
+		if feature == name {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,62 @@
+package minimax
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// weights written by TUNE are read back unchanged
+func TestWeightsRoundTrip(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "weights")
+	want := NewLinear([FEATURE_COUNT]float64{1, 1.0 / 3, -0.0025, math.MaxFloat64})
+	want.WriteWeights(fileName, "round trip")
+
+	if got := ReadWeights(fileName); *got != *want {
+		t.Errorf("%v; want %v", got.Weights, want.Weights)
+	}
+}
+
+// comments and empty lines are skipped, missing features are 0
+func TestReadWeights(t *testing.T) {
+	linear, err := readWeights(strings.NewReader("# comment\n\nseeds 0.5 # trailing comment\n  vulnerable\t-2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [FEATURE_COUNT]float64{0, 0.5, 0, -2}; linear.Weights != want {
+		t.Errorf("%v; want %v", linear.Weights, want)
+	}
+	if linear.String() != LINEAR {
+		t.Errorf("name: %s; want %s", linear, LINEAR)
+	}
+
+	for _, text := range []string{"stones 1", "seeds", "seeds 1 2", "seeds one", "seeds 1\nmobility 0,5"} {
+		if _, err := readWeights(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: no error", text)
+		}
+	}
+}
+
+// the built-in evaluators are registered; others are selectable once registered
+func TestEvaluation(t *testing.T) {
+	for _, name := range []string{PARITY, MATERIAL} {
+		if evaluator, ok := Evaluation(name); !ok || evaluator.String() != name {
+			t.Errorf("%s: %v, %v", name, evaluator, ok)
+		}
+	}
+	if evaluator, ok := Evaluation("none"); ok {
+		t.Errorf("none: %v", evaluator)
+	}
+
+	linear := NewLinear(MaterialWeights)
+	Register(linear)
+	defer func() {
+		evaluatorsMutex.Lock()
+		delete(evaluators, LINEAR)
+		evaluatorsMutex.Unlock()
+	}()
+	if evaluator, ok := Evaluation(LINEAR); !ok || evaluator != linear {
+		t.Errorf("%s: %v, %v; want the registered one", LINEAR, evaluator, ok)
+	}
+}
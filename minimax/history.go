@@ -0,0 +1,126 @@
+package minimax
+
+// path dependence of scores: the graph-history interaction of cycles
+//
+// A repeated position ends the game, v. Game.Cycle(), so the score of a cycle depends on the game leading to it.
+// The transposition table stores scores by rank: a position reached by another history could inherit a score
+// that does not apply, in both directions:
+//   - a cycle below was scored, but the other history does not contain the repeated position
+//   - no cycle was found below, but the other history contains a position that the search below reaches
+//
+// Remedy:
+//   - negaMax reports the earliest position of the game that a cycle below relied on, NO_CYCLE if none
+//   - an interval relying on positions before its own records how many plies back, and a hash of their boards
+//   - an interval whose search made a move without capture could reach any earlier position with as many stones:
+//     it records all of them, back to the last capture (level path)
+//   - a search uses such an interval only if it reached the position by the same plies, for a level path by the same
+//     plies since the last capture; otherwise the interval is refused, the position searched anew
+//     and the new interval replaces the old one
+//
+// Only positions with the same number of stones can repeat: the plies recorded never reach back beyond a capture.
+// Intervals found by captures only, and those of terminal positions, apply to any history.
+// The previous iteration's intervals only order the moves, so they are used regardless of the history.
+
+import (
+	"sankofa/mech"
+	"sankofa/ow"
+)
+
+// no cycle below
+const NO_CYCLE = ow.MAXINT
+
+// the plies before a position that its score relies on; the zero value for none
+type path struct {
+	plies int
+	hash  uint64
+	level bool // the plies are all the positions since the last capture
+}
+
+// the plies of the game back to the reach of a cycle; none if the cycle is reached from the current position
+func pathOf(game *mech.Game, reach int) path {
+	if reach >= game.Cursor {
+		return path{}
+	}
+	return path{game.Cursor - reach, boardsHash(game.Positions[reach:game.Cursor]), false}
+}
+
+// index of the earliest position of the game with as many stones as the current one, i.e., after the last capture
+func levelStart(game *mech.Game) int {
+	stones := game.Current().Stones()
+	i := game.Cursor
+	for i > 0 && game.Positions[i-1].Stones() == stones {
+		i--
+	}
+	return i
+}
+
+// the plies of the game since the last capture; positions reached below without a capture may repeat them
+func levelPathOf(game *mech.Game) path {
+	start := levelStart(game)
+	return path{game.Cursor - start, boardsHash(game.Positions[start:game.Cursor]), true}
+}
+
+// FNV-1a hash of the boards
+func boardsHash(positions []*mech.Position) uint64 {
+	hash := uint64(14695981039346656037)
+	for _, position := range positions {
+		for _, stones := range position.Board {
+			hash ^= uint64(uint8(stones))
+			hash *= 1099511628211
+		}
+	}
+	return hash
+}
+
+// does the interval apply to the game's history?
+func (interval *Interval) applies(game *mech.Game) bool {
+	switch {
+	case interval.path.level:
+		return interval.path == levelPathOf(game)
+	case interval.path.plies == 0:
+		return true
+	default:
+		return interval.path.plies <= game.Cursor && interval.path == pathOf(game, game.Cursor-interval.path.plies)
+	}
+}
+
+// earliest position of the game the interval relies on; NO_CYCLE if none
+func (interval *Interval) reach(game *mech.Game) int {
+	if interval.path.plies == 0 && !interval.path.level {
+		return NO_CYCLE
+	}
+	return game.Cursor - interval.path.plies
+}
+
+// does the score depend on the history?
+func (interval *Interval) PathDependent() bool {
+	return interval.path.plies > 0 || interval.path.level
+}
+
+// interval of the game's current position, if any and if it applies to the game's history
+func (tt *TT) lookup(game *mech.Game) *Interval {
+	interval := tt.Interval(game.Current().Rank())
+	if interval == nil || interval.applies(game) {
+		return interval
+	}
+	ow.Log(game, "refused: other history:", interval)
+	tt.incRefused()
+	return nil
+}
+
+// count an interval refused for another history
+func (tt *TT) incRefused() *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.refused++
+	return tt
+}
+
+// intervals refused for another history
+func (tt *TT) Refused() int {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	return tt.refused
+}
@@ -0,0 +1,110 @@
+package minimax
+
+import (
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"testing"
+)
+
+// two games of as many plies reaching the same position by different positions
+func transposition(plies int) (*mech.Game, *mech.Game) {
+	seen := map[int64]*mech.Game{}
+	games := []*mech.Game{mech.StringToGame(INITIAL)}
+	for ply := 0; ply < plies; ply++ {
+		var next []*mech.Game
+		for _, game := range games {
+			for _, move := range game.Current().LegalMoves().Moves {
+				next = append(next, game.Move(move))
+			}
+		}
+		games = next
+	}
+	for _, game := range games {
+		if game.GameOver() {
+			continue
+		}
+		rank := game.Current().Rank()
+		if other, ok := seen[rank]; ok && pathOf(other, 0) != pathOf(game, 0) {
+			return other, game
+		}
+		seen[rank] = game
+	}
+	return nil, nil
+}
+
+// exact score of the current position, relying on the given path
+func saveExact(tt *TT, game *mech.Game, path path) {
+	rank := game.Current().Rank()
+	level := ow.Level(rank)
+	tt.save(rank, -level, level, 0, mech.OPEN, 1, path)
+}
+
+// a score found without a cycle, but by moves without capture, is refused once the position has another history since the last capture
+func TestLevelPath(t *testing.T) {
+	// a move without capture
+	after := mech.StringToGame(INITIAL + "/D")
+	if after.Current().Stones() != after.First().Stones() {
+		t.Fatal("captured:", after)
+	}
+	// the same position, without a history
+	fresh := mech.StringToGame("/" + strconv.FormatInt(after.Current().Rank(), 10))
+
+	tt := NewTT(fresh)
+	saveExact(tt, fresh, levelPathOf(fresh))
+	if tt.lookup(fresh) == nil {
+		t.Error("refused for its own history")
+	}
+	if tt.lookup(after) != nil {
+		t.Error("applies after an earlier position that may repeat")
+	}
+	if tt.Refused() != 1 {
+		t.Errorf("refused: %d; want 1", tt.Refused())
+	}
+
+	// and vice versa
+	tt = NewTT(after)
+	saveExact(tt, after, levelPathOf(after))
+	if tt.lookup(after) == nil {
+		t.Error("refused for its own history")
+	}
+	if tt.lookup(fresh) != nil {
+		t.Error("applies without the position it may repeat")
+	}
+}
+
+// a cycle's score is used only after the same plies
+func TestCyclePath(t *testing.T) {
+	one, other := transposition(6)
+	if one == nil {
+		t.Fatal("no transposition")
+	}
+	fresh := mech.StringToGame("/" + strconv.FormatInt(one.Current().Rank(), 10))
+
+	tt := NewTT(one)
+	// relies on the first position of the game
+	saveExact(tt, one, pathOf(one, 0))
+	if interval := tt.lookup(one); interval == nil || !interval.PathDependent() {
+		t.Errorf("%v: %v; want a path dependent interval", one, interval)
+	}
+	for i, game := range []*mech.Game{other, fresh, one, other} {
+		interval := tt.lookup(game)
+		if (interval != nil) != (game == one) {
+			t.Errorf("%v: %v", game, interval)
+		}
+		if refused := []int{1, 2, 2, 3}[i]; tt.Refused() != refused {
+			t.Errorf("%v: refused: %d; want %d", game, tt.Refused(), refused)
+		}
+	}
+
+	// scores without a path apply to any history
+	saveExact(tt, one, path{})
+	for _, game := range []*mech.Game{one, other, fresh} {
+		if tt.lookup(game) == nil {
+			t.Errorf("%v: refused", game)
+		}
+	}
+	if tt.Refused() != 3 {
+		t.Errorf("refused: %d; want 3", tt.Refused())
+	}
+}
@@ -10,6 +10,7 @@ type Interval struct {
 	rank      int64
 	low, high int8 // expected score interval
 	verdict   int8 // unknown/loss/draw/win
+	path      path // plies before the position the score relies on, v. history.go
 }
 
 // for logging and HTML output
@@ -56,7 +57,9 @@ func NewInterval(rank int64, low, high, verdict int8) *Interval {
 }
 
 func (interval *Interval) Clone() *Interval {
-	return NewInterval(interval.rank, interval.low, interval.high, interval.verdict)
+	r := NewInterval(interval.rank, interval.low, interval.high, interval.verdict)
+	r.path = interval.path
+	return r
 }
 
 // reverse the interval; score range from the perspective of the opponent
@@ -84,10 +87,32 @@ func (interval *Interval) Score() int8 {
 	return ow.MININT8
 }
 
+// fail-soft bound that falls outside the α—β window; false if the interval overlaps the window
+func (interval *Interval) Bound(α, β int8) (int8, bool) {
+	switch {
+	case interval.low >= β:
+		return interval.low, true
+	case interval.high <= α:
+		return interval.high, true
+	default:
+		return ow.ZERO8, false
+	}
+}
+
 func (interval *Interval) Verdict() int8 {
 	return interval.verdict
 }
 
+// lower bound of the score
+func (interval *Interval) Low() int8 {
+	return interval.low
+}
+
+// upper bound of the score
+func (interval *Interval) High() int8 {
+	return interval.high
+}
+
 func (interval *Interval) Plus(x int8) *Interval {
 	r := interval.Clone()
 	r.low += x
@@ -1,33 +1,75 @@
 package minimax
 
 import (
+	"context"
 	"fmt"
+	"math"
+	"sankofa/mech"
 	"sankofa/ow"
+	"strconv"
 	"time"
 )
 
-// go routine that kills the iterative deepener when the timer expires
-func (tt *TT) Terminator(limit float64) {
+// context cancelled by the caller or when the time limit expires, if limit > 0
+func withDuration(ctx context.Context, limit float64) (context.Context, context.CancelFunc) {
+	if limit <= 0 {
+		ow.Log("no time limit")
+		return context.WithCancel(ctx)
+	}
 	ow.Log("terminate after:", limit)
-	time.Sleep(time.Duration(limit * ow.GIGA))
+	return context.WithTimeout(ctx, time.Duration(limit*ow.GIGA))
+}
 
-	// transaction
-	tt.AbortDeepener()
-	fmt.Println("watchdog")
+// duration limit of Explore(): search until the context is cancelled
+var INFINITE = math.Inf(1)
+
+// iterative depender with duration limit; result in transposition table;
+// as ever, a limit ≤ 0 stops at once; v. INFINITE
+func (tt *TT) Explore(ctx context.Context, goroutines int, limit float64) *TT {
+	switch {
+	case limit == INFINITE:
+		return tt.ExploreLimits(ctx, goroutines, Limits{})
+	case limit <= 0:
+		ow.Log("no time:", limit)
+		stopped, cancel := context.WithCancel(ctx)
+		cancel()
+		return tt.ExploreLimits(stopped, goroutines, Limits{})
+	}
+	return tt.ExploreLimits(ctx, goroutines, Limits{Duration: limit})
 }
 
-// iterative depender with duration limit; result in transposition table
-func (tt *TT) Explore(goroutines int, limit float64) *TT {
+// iterative depender with combined limits, v. Limits; result in transposition table, v. *TT.Result();
+// unlike the duration of Explore(), zero limits mean none;
+// cancelling the context stops the search and discards the unfinished iteration;
+// each completed iteration is reported to the progress callback, v. *TT.SetProgress();
+// book positions are not searched, v. Book
+func (tt *TT) ExploreLimits(ctx context.Context, goroutines int, limits Limits) *TT {
 	// to restore initial conditions
 	game := tt.Game()
+	limits = limits.deterministic()
+	tt.setLimits(limits)
+
+	// no goroutine outlives the search
+	ctx, cancel := withDuration(ctx, limits.Duration)
+	defer cancel()
+	tt.mutex.Lock()
+	tt.setDeepener(ctx)
+	tt.mutex.Unlock()
+	fmt.Println("limits:", limits)
+
+	// opening book first
+	if tt.fromBook(game, limits) {
+		tt.report()
+		return tt
+	}
 
 	// the number of stones on the board limits the interval and is used in computing the SD
 	level := ow.Level(tt.Game().Current().Rank())
 
-	// -t Trace disables parallelism
-	if Trace {
+	// -t Trace, the deterministic mode and recording the tree disable parallelism
+	if Trace || Deterministic || tt.Recording() {
 		goroutines = 1
-		ow.Log("trace: no parallism:", goroutines, "goroutines")
+		ow.Log("trace, deterministic or recording: no parallism:", goroutines, "goroutines")
 	}
 
 	// make copies, since Alpha and Beta will be used in further iterations
@@ -35,30 +77,81 @@ func (tt *TT) Explore(goroutines int, limit float64) *TT {
 	a := ow.Max(-level, ow.Min(level, Alpha))
 	b := ow.Max(-level, ow.Min(level, Beta))
 
-	// split in so many intervals as many processor cores are available
-	intervals := Quartiles(a, b, level, goroutines)
+	var intervals Intervals
+	switch Search {
+	case ASPIRATION:
+		// split in so many intervals as many processor cores are available
+		intervals = Quartiles(a, b, level, goroutines)
+	case MTDF:
+		ow.Log("MTD(f): single-threaded")
+	case LAZYSMP:
+		ow.Log("lazy SMP:", goroutines, "goroutines")
+	default:
+		ow.Panic("no such search driver:", Search)
+	}
 
 	// iterative deepening
-	go tt.Terminator(limit)
 	for depth := 2; !tt.DeepenerAborted(); depth += 1 {
 		// need transaction here, not to lose .abort!!!
-		tt = tt.Restart().setGame(game).Aspiration(intervals, depth)
+		tt = tt.Restart().setGame(game)
+		iteration := tt.iterationContext()
+		switch Search {
+		case MTDF:
+			tt = tt.MTDF(iteration, depth)
+		case LAZYSMP:
+			tt = tt.LazySMP(iteration, a, b, goroutines, depth)
+		default:
+			tt = tt.Aspiration(iteration, intervals, depth)
+		}
+		tt.AbortIteration()
 		ow.Log(tt.Game(), "depth:", depth)
 
 		if tt.Base() > 0 {
 			fmt.Println("base:", tt.Base(), "above bottom: break")
+			tt.report()
+			tt.keep()
 			ow.Log("base:", tt.Base(), "depth:", depth)
 			break
 		}
 
 		if tt.DeepenerAborted() {
-			ow.Log("watchdog: break and discard unfinished iteration")
+			if ctx.Err() == context.DeadlineExceeded {
+				fmt.Println("watchdog")
+			}
+			ow.Log("cancelled: break and discard unfinished iteration")
 			tt = tt.old
 			break
 		}
 		fmt.Println(tt)
+		tt.report()
+		tt.keep()
+
+		if limits.Verdict {
+			if interval := tt.Interval(game.Current().Rank()); interval != nil &&
+				(interval.Verdict() == mech.WIN || interval.Verdict() == mech.LOSS) {
+				fmt.Println("verdict:", mech.VerdictToString(interval.Verdict()), "proven: break")
+				break
+			}
+		}
+		if limits.Depth > 0 && depth >= limits.Depth {
+			fmt.Println("depth:", depth, "reached: break")
+			break
+		}
 	}
 	ow.Log("transposition:", tt)
 
+	if tt.cache != nil {
+		fmt.Println("cache:", tt.cache)
+	}
+
+	// statistics for comparing the search drivers
+	fmt.Println("search:", Search,
+		"| depth:", ow.Thousands(tt.Depth()-tt.Base()),
+		"| nodes:", ow.Thousands(tt.Nodes()),
+		"| quiescence:", ow.Thousands(tt.Quiescence()),
+		"| first-move cut-offs:", FirstMoveRate(tt.CutOffs()),
+		"|", strconv.FormatFloat(tt.Elapsed(), 'f', 2, 64), "sec.",
+		"|", strconv.FormatFloat(float64(tt.Nodes())/tt.Elapsed(), 'f', 0, 64), "nodes/sec.")
+
 	return tt
 }
@@ -0,0 +1,22 @@
+package minimax
+
+import (
+	"context"
+	"sankofa/mech"
+	"testing"
+	"time"
+)
+
+// Explore(): no time stops at once, INFINITE searches until cancelled
+func TestExploreDuration(t *testing.T) {
+	game := mech.StringToGame(INITIAL)
+	if result := NewTT(game).Explore(context.Background(), 1, 0).Result(); result.Depth != 0 || result.Move != NO_MOVE {
+		t.Errorf("no time: %v", &result.SearchInfo)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if result := NewTT(game).Explore(ctx, 1, INFINITE).Result(); result.Depth < 2 || ctx.Err() == nil {
+		t.Errorf("infinite: %v, cancelled: %v", &result.SearchInfo, ctx.Err())
+	}
+}
@@ -11,12 +11,12 @@ func (tt *TT) _position(rank int64) *mech.Position {
 	var r *mech.Position
 
 	// get it if available
-	r, ok := tt.positions[rank]
+	r, ok := tt.memo.positions.Get(rank)
 
 	if !ok {
 		// or create it if not
 		r = mech.Unrank(rank)
-		tt.positions[rank] = r
+		tt.memo.positions.Put(rank, 0, r)
 	}
 
 	return r
@@ -24,9 +24,6 @@ func (tt *TT) _position(rank int64) *mech.Position {
 
 // position for given rank; lazy memoization
 func (tt *TT) Position(rank int64) *mech.Position {
-	tt.mutex.Lock()
-	defer tt.mutex.Unlock()
-
 	return tt._position(rank)
 }
 
@@ -34,18 +31,16 @@ func (tt *TT) Position(rank int64) *mech.Position {
 func (tt *TT) LegalMoves(rank int64) *mech.LegalMoves {
 	var r *mech.LegalMoves
 
-	tt.mutex.Lock()
-	defer tt.mutex.Unlock()
+	tt.cntLegalMoves.Add(1)
 
 	// get it if available
-	r, ok := tt.legalMoves[rank]
-	tt.cntLegalMoves += 1
+	r, ok := tt.memo.legalMoves.Get(rank)
 
 	if !ok {
 		// or create it if not
 		p := tt._position(rank)
 		r = p.LegalMoves()
-		tt.legalMoves[rank] = r
+		tt.memo.legalMoves.Put(rank, 0, r)
 	}
 
 	return r.Clone()
@@ -55,64 +50,155 @@ func (tt *TT) LegalMoves(rank int64) *mech.LegalMoves {
 func (tt *TT) MovesInHand(rank int64) int8 {
 	var r int8
 
-	tt.mutex.Lock()
-	defer tt.mutex.Unlock()
+	tt.cntMovesInHand.Add(1)
 
 	// get it if available
-	r, ok := tt.movesInHand[rank]
-	tt.cntMovesInHand += 1
+	r, ok := tt.memo.movesInHand.Get(rank)
 
 	if !ok {
 		// or create it if not
 		r = tt._position(rank).MovesInHand()
-		tt.movesInHand[rank] = r
+		tt.memo.movesInHand.Put(rank, 0, r)
 	}
 
 	return r
 }
 
-// sorted list of best/killer moves for a given rank
-func (tt *TT) KillerMoves(rank int64) []int8 {
-	legalMoves := tt.LegalMoves(rank)
+////////////////////////////////////////////////////////////////
+// MOVE ORDERING
+////////////////////////////////////////////////////////////////
 
-	// re-sort, since the contextual information might have changed
-	// WARNING without numeric sorting, the order is random and the results with 1 thread not predictible
-	sort.Slice(legalMoves.Moves, func(a, b int) bool {
-		moveA := legalMoves.Moves[a]
-		moveB := legalMoves.Moves[b]
+// killer-move slots per ply
+const KILLERS = 2
 
-		rankA := legalMoves.Next[moveA]
-		rankB := legalMoves.Next[moveB]
+// move ordering keys of a move, in the order of precedence
+type sortKey struct {
+	move     int8
+	estimate int  // twice the expected score: captures less the successor's interval midpoint, v. hint()
+	killer   int  // KILLERS for the first slot, ..., 1 for the last one, 0 if none
+	history  int  // cut-offs weighted by depth²
+	ν        int8 // the opponent's moves in hand after the move
+}
 
-		var intervalA, intervalB *Interval
-		if tt.old != nil {
-			intervalA = tt.old.Interval(rankA)
-			intervalB = tt.old.Interval(rankB)
-			ow.Log(intervalA, ">?", intervalB)
+// does key a sort before key b?
+func (a *sortKey) before(b *sortKey) bool {
+	switch {
+	case a.estimate != b.estimate:
+		return a.estimate > b.estimate
+	case a.killer != b.killer:
+		return a.killer > b.killer
+	case a.history != b.history:
+		return a.history > b.history
+	case a.ν != b.ν:
+		// preserve MIH; LT since we have the opponent's perspective!
+		return a.ν < b.ν
+	default:
+		// base line: numeric sort
+		return a.move > b.move
+	}
+}
+
+// sorted list of best/killer moves for the current position of a game;
+// the keys are computed once per move, not in the comparator
+func (tt *TT) KillerMoves(game *mech.Game) []int8 {
+	legalMoves := tt.LegalMoves(game.Current().Rank())
+
+	keys := make([]sortKey, len(legalMoves.Moves))
+	for i, move := range legalMoves.Moves {
+		next := legalMoves.Next[move]
+		keys[i].move = move
+		keys[i].estimate = 2 * int(legalMoves.Score[move])
+		if interval := tt.hint(next); interval != nil {
+			keys[i].estimate -= int(interval.low) + int(interval.high)
 		}
+		keys[i].ν = tt.MovesInHand(next)
+	}
 
-		// sort criteria: TT, captures, feeding, front-back
-		switch {
-		case intervalA != nil && intervalB != nil && intervalA.Disjoint(intervalB):
-			ow.Log("sort: score:", legalMoves, "|", mech.MoveToString(moveA), mech.MoveToString(moveB), "|", intervalA, intervalB)
-			ow.Log(moveA, " ⇢ ", intervalA)
-			ow.Log(moveB, " ⇢ ", intervalB)
-			return intervalA.Gt(intervalB)
-		case legalMoves.Score[moveA] != legalMoves.Score[moveB]:
-			ow.Log("sort: captures:", legalMoves, "|", mech.MoveToString(moveA), mech.MoveToString(moveB))
-			return legalMoves.Score[moveA] > legalMoves.Score[moveB]
-		case tt.MovesInHand(rankA) != tt.MovesInHand(rankB):
-			// preserve MIH; LT since we have the opponent's perspective!
-			ow.Log("sort: Δν:", legalMoves, "|", mech.MoveToString(moveA), mech.MoveToString(moveB), "|", tt.MovesInHand(rankA), tt.MovesInHand(rankB))
-			return tt.MovesInHand(rankA) < tt.MovesInHand(rankB)
-		default:
-			// base line: numeric sort
-			ow.Log("sort: numerical:", legalMoves, "|", mech.MoveToString(moveA), mech.MoveToString(moveB))
-			return moveA > moveB
+	// killer and history heuristics: one transaction
+	ply, side := tt.ply(game), game.Cursor%2
+	tt.mutex.RLock()
+	for i := range keys {
+		if ply < len(tt.killers) {
+			for slot, killer := range tt.killers[ply] {
+				if killer == keys[i].move {
+					keys[i].killer = KILLERS - slot
+				}
+			}
 		}
+		keys[i].history = tt.history[side][keys[i].move]
+	}
+	tt.mutex.RUnlock()
+
+	sort.Slice(keys, func(a, b int) bool {
+		return keys[a].before(&keys[b])
 	})
 
-	ow.Log("sorted legal moves:", legalMoves)
+	r := make([]int8, len(keys))
+	for i := range keys {
+		r[i] = keys[i].move
+	}
+	ow.Log("sorted legal moves:", legalMoves, "⇢", r)
+
+	return r
+}
+
+// distance from the root
+func (tt *TT) ply(game *mech.Game) int {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
 
-	return legalMoves.Moves
+	return game.Cursor - tt.game.Cursor
+}
+
+// a move causing a cut-off: counters, killer slots and history for quiet moves;
+// first: the move was searched first
+func (tt *TT) cutOffBy(game *mech.Game, move int8, capture bool, first bool, depth int) *TT {
+	ply, side := tt.ply(game), game.Cursor%2
+
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.cutOff++
+	if first {
+		tt.firstCutOff++
+	}
+	if capture || ply < 0 {
+		// captures are ordered by their estimate
+		return tt
+	}
+
+	// killers: most recent first, no duplicates
+	for len(tt.killers) <= ply {
+		tt.killers = append(tt.killers, [KILLERS]int8{NO_MOVE, NO_MOVE})
+	}
+	killers := &tt.killers[ply]
+	if killers[0] != move {
+		copy(killers[1:], killers[:KILLERS-1])
+		killers[0] = move
+	}
+
+	// history: deeper cut-offs weigh more
+	d := ow.Max(1, depth)
+	tt.history[side][move] += d * d
+	return tt
+}
+
+// killer moves in the order of a lazy-SMP helper:
+// the best move first, the others rotated by the helper number;
+// a helper never takes the main search's order, rotation 0
+func (tt *TT) helperMoves(game *mech.Game, helper int) []int8 {
+	moves := tt.KillerMoves(game)
+	if helper == 0 || len(moves) < 3 {
+		return moves
+	}
+
+	r := make([]int8, 0, len(moves))
+	r = append(r, moves[0])
+	// rotations 1..len(moves)-2 of the moves after the first
+	shift := 2 + (helper-1)%(len(moves)-2)
+	r = append(r, moves[shift:]...)
+	r = append(r, moves[1:shift]...)
+
+	ow.Log("helper:", helper, "moves:", r)
+	return r
 }
@@ -0,0 +1,92 @@
+package minimax
+
+import (
+	"sankofa/mech"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+// the initial position
+const INITIAL = "/1224204106872"
+
+// the first game, from the lowest rank, whose position has the given number of legal moves
+func gameWithMoves(moves int) *mech.Game {
+	for rank := int64(0); ; rank++ {
+		if len(mech.Unrank(rank).LegalMoves().Moves) == moves {
+			return mech.StringToGame("/" + strconv.FormatInt(rank, 10))
+		}
+	}
+}
+
+// lazy-SMP helpers keep the best move first and never search in the main order
+func TestHelperMoves(t *testing.T) {
+	for _, game := range []*mech.Game{gameWithMoves(3), gameWithMoves(4), mech.StringToGame(INITIAL)} {
+		tt := NewTT(game)
+		main := tt.KillerMoves(game)
+		for helper := 1; helper < 10; helper++ {
+			moves := tt.helperMoves(game, helper)
+			if moves[0] != main[0] || slices.Equal(moves, main) {
+				t.Errorf("%v, helper %d: %v; main: %v", game, helper, moves, main)
+			}
+			sorted := slices.Clone(moves)
+			slices.Sort(sorted)
+			if want := slices.Sorted(slices.Values(main)); !slices.Equal(sorted, want) {
+				t.Errorf("%v, helper %d: %v; not a permutation of %v", game, helper, moves, main)
+			}
+		}
+	}
+}
+
+// quiet cut-offs: the killers of the ply come first, the most recent one first; captures are not killers
+func TestKillers(t *testing.T) {
+	game := mech.StringToGame(INITIAL)
+	tt := NewTT(game)
+	moves := tt.KillerMoves(game)
+
+	last, beforeLast := moves[len(moves)-1], moves[len(moves)-2]
+	tt.cutOffBy(game, last, false, false, 1)
+	if got := tt.KillerMoves(game); got[0] != last {
+		t.Errorf("killer %s: %v", mech.MoveToString(last), got)
+	}
+	tt.cutOffBy(game, beforeLast, false, false, 1)
+	if got := tt.KillerMoves(game); got[0] != beforeLast || got[1] != last {
+		t.Errorf("killers %s, %s: %v", mech.MoveToString(beforeLast), mech.MoveToString(last), got)
+	}
+	tt.cutOffBy(game, moves[0], true, true, 1)
+	if got := tt.KillerMoves(game); got[0] != beforeLast || got[1] != last {
+		t.Errorf("capture became a killer: %v", got)
+	}
+
+	// another ply of the same side: no killers, but the history
+	later := game.Move(moves[0])
+	later = later.Move(tt.KillerMoves(later)[0])
+	if got := tt.KillerMoves(later); got[0] != beforeLast && got[0] != last {
+		t.Errorf("history of %s, %s: %v", mech.MoveToString(beforeLast), mech.MoveToString(last), got)
+	}
+}
+
+// the history weighs cut-offs by depth², the other side's not at all
+func TestHistory(t *testing.T) {
+	game := mech.StringToGame(INITIAL)
+	tt := NewTT(game)
+	moves := tt.KillerMoves(game)
+
+	// another ply, so that killers do not interfere
+	later := game.Move(moves[0])
+	later = later.Move(tt.KillerMoves(later)[0])
+	shallow, deep := moves[len(moves)-1], moves[len(moves)-2]
+	for i := 0; i < 3; i++ {
+		tt.cutOffBy(later, shallow, false, false, 1)
+	}
+	tt.cutOffBy(later, deep, false, false, 2)
+	if got := tt.KillerMoves(game); got[0] != deep || got[1] != shallow {
+		t.Errorf("history: %s 4, %s 3: %v", mech.MoveToString(deep), mech.MoveToString(shallow), got)
+	}
+
+	// the other side
+	other := game.Move(moves[0])
+	if got, want := tt.KillerMoves(other), NewTT(game).KillerMoves(other); !slices.Equal(got, want) {
+		t.Errorf("history of the other side: %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package minimax
+
+// Lazy SMP: all goroutines search the same root with the full window and share the transposition table.
+// Helpers visit the moves in slightly different orders and thereby fill the table for each other.
+// The main search (helper 0) sets the result; the helpers are stopped when it is done.
+//
+// The odd helpers search one ply deeper, on a table they share: the intervals of the next iteration.
+// Thus the scores of this iteration do not mix with deeper ones, and the next iteration starts from the subtrees done ahead.
+//
+// Unlike the parallel aspiration, no worker finishes early with a fail-low or a fail-high:
+// every core contributes to the depth of the search.
+
+import (
+	"context"
+	"fmt"
+	"sankofa/mech"
+	"sankofa/ow"
+)
+
+// search driver: shared transposition table
+const LAZYSMP = "smp"
+
+// goroutine that runs a helper search on the table of this iteration or of the next one
+func (tt *TT) helper(ctx context.Context, table *TT, α, β int8, depth, helper int) {
+	ow.Log("helper:", helper, "α:", α, ", β:", β, ", depth:", depth)
+	table.negaMax(ctx, table.Game(), α, β, depth, helper)
+
+	// signal finished event to the WaitGroup
+	tt.Dec()
+}
+
+// lazy SMP for a given depth; result in transposition table
+func (tt *TT) LazySMP(ctx context.Context, α, β int8, goroutines, depth int) *TT {
+	ow.Log(tt.Game(), "goroutines:", goroutines, "depth:", depth)
+
+	// odd helpers search ahead
+	var ahead *TT
+	if goroutines > 1 {
+		ahead = tt.fork()
+	}
+	for helper := 1; helper < goroutines; helper++ {
+		tt.Inc()
+		if helper%2 == 1 {
+			go tt.helper(ctx, ahead, α, β, depth+1, helper)
+		} else {
+			go tt.helper(ctx, tt, α, β, depth, helper)
+		}
+	}
+
+	// main search
+	root := tt.Game()
+	score, verdict, game, _ := tt.negaMax(ctx, root, α, β, depth, 0)
+	game.Cursor = root.Cursor
+	fmt.Println(ow.Thousands(α, β), "⇢", tt.Interval(root.Current().Rank()), game)
+
+	// stop the helpers
+	tt.mutex.Lock()
+	if !tt._iterationAborted() {
+		tt._abortIteration()
+	}
+	tt.mutex.Unlock()
+	tt.Wait()
+	fmt.Println("all helpers done")
+
+	fmt.Println("continuation ⇢", game, "score±heuristic:", mech.VerdictToString(verdict), score)
+	tt.mutex.Lock()
+	tt._setGame(game)
+	tt.found = true
+	if ahead != nil {
+		tt._searchedAhead(ahead)
+	}
+	tt.mutex.Unlock()
+
+	return tt
+}
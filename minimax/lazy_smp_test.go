@@ -0,0 +1,47 @@
+package minimax
+
+import (
+	"context"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"testing"
+)
+
+// a helper that finishes one ply deeper than the main search starts the next iteration:
+// its intervals answer nodes below the root's successors, and the root score is that of a search from scratch
+func TestLazySMPAhead(t *testing.T) {
+	defer func(search string) { Search = search }(Search)
+	Search = ASPIRATION
+
+	for _, trail := range deterministicTrails {
+		game := mech.StringToGame(trail)
+		level := ow.Level(game.Current().Rank())
+		for depth := 2; depth <= 5; depth++ {
+			name := trail + ", depth " + strconv.Itoa(depth)
+
+			// not the iteration's context: the main search does not stop the helper, which finishes
+			tt := NewTT(game).Restart().setGame(game)
+			tt.LazySMP(context.Background(), -level, level, 2, depth)
+
+			next := tt.Restart().setGame(game)
+			entries := 0
+			next.tt.Range(func(int64, int, *Interval) { entries++ })
+			if entries == 0 {
+				t.Errorf("%s: nothing searched ahead", name)
+			}
+			next.LazySMP(next.iterationContext(), -level, level, 1, depth+1)
+
+			want := search(trail, 1, Limits{Depth: depth + 1})
+			if got := next.Interval(game.Current().Rank()); got.String() != want.Interval.String() {
+				t.Errorf("%s: %v; want %v", name, got, want.Interval)
+			}
+			if pv := next.Game(); len(pv.Moves) <= game.Cursor {
+				t.Errorf("%s: no move: %v", name, pv)
+			}
+			if next.Base() != 0 {
+				t.Errorf("%s: base %d; the bottom was reached ahead", name, next.Base())
+			}
+		}
+	}
+}
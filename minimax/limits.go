@@ -0,0 +1,68 @@
+package minimax
+
+// search limits of the iterative deepener; they can be combined and the first one reached stops the search:
+//   - Duration: wall clock, in seconds
+//   - Depth: the last deepener iteration
+//   - Nodes: visited nodes, all iterations
+//   - Verdict: a proven win or loss at the root
+//
+// Without any limit, the search is infinite; it ends when the caller cancels the context.
+// Depth and node limits give the same results on any machine, unlike the wall clock.
+// An iteration stopped by the duration or node limit is discarded.
+
+import (
+	"sankofa/ow"
+	"strconv"
+)
+
+// zero values mean no limit
+type Limits struct {
+	Duration float64
+	Depth    int
+	Nodes    int
+	Verdict  bool
+}
+
+// infinite search, only stopped by cancelling the context
+func (limits Limits) Infinite() bool {
+	return limits.Duration <= 0 && limits.Depth <= 0 && limits.Nodes <= 0 && !limits.Verdict
+}
+
+func (limits Limits) String() string {
+	if limits.Infinite() {
+		return "infinite"
+	}
+
+	var r string
+	if limits.Duration > 0 {
+		r += " " + strconv.FormatFloat(limits.Duration, 'f', -1, 64) + " sec."
+	}
+	if limits.Depth > 0 {
+		r += " depth: " + ow.Thousands(limits.Depth)
+	}
+	if limits.Nodes > 0 {
+		r += " nodes: " + ow.Thousands(limits.Nodes)
+	}
+	if limits.Verdict {
+		r += " verdict"
+	}
+	return r[1:]
+}
+
+// set the limits
+func (tt *TT) setLimits(limits Limits) *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.limits = limits
+	return tt
+}
+
+// search limits
+func (tt *TT) Limits() Limits {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	r := tt.limits
+	return r
+}
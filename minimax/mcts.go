@@ -0,0 +1,411 @@
+package minimax
+
+// Monte Carlo tree search (UCT): an alternative, statistical view of a position
+//
+// Far from the database levels, the heuristic at the bottom of α—β says little about the outcome.
+// MCTS instead plays many games to the end and counts who wins:
+//   - selection: UCT, the win rate plus Exploration × √(ln parent visits / visits)
+//   - expansion: one untried move at a time, in random order
+//   - playout: random moves or, with the greedy policy, the biggest capture if any, v. Playout
+//   - outcome: 25 stones, starvation, cycles and the database end a playout; so does MCTS_PLIES, splitting the stones
+//
+// Root parallelism: each goroutine grows its own tree with its own random numbers; the root statistics are summed up.
+// The duration limit and the node limit, counted in playouts (Playouts by default), apply.
+// In deterministic mode, the random numbers are seeded from ow.Seed and the duration is ignored, v. Deterministic.
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sankofa/db"
+	"sankofa/mech"
+	"sankofa/ow"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// analysers selectable in the web UI
+const (
+	MINIMAX = "minimax"
+	MCTS    = "mcts"
+)
+
+// playout policies
+const (
+	RANDOM = "random"
+	GREEDY = "greedy"
+)
+
+// default playouts of MCTS, unless limited by nodes
+var Playouts = 100000
+
+// UCT exploration constant
+var Exploration = math.Sqrt2
+
+// playout policy
+var Playout = GREEDY
+
+// plies after which a playout ends with the stones split
+const MCTS_PLIES = 200
+
+// a root move with its statistics
+type MCTSLine struct {
+	Move    int8       // house moved, from the perspective of the side to move
+	Visits  int        // playouts through the move
+	WinRate float64    // for the side to move; draws count half
+	Game    *mech.Game // most visited continuation; cursor at the root
+}
+
+// outcome of a Monte Carlo tree search
+type MCTSResult struct {
+	Lines    []*MCTSLine // root moves, most visited first
+	Playouts int
+	Elapsed  float64 // seconds
+}
+
+func (line *MCTSLine) String() string {
+	return mech.MoveToString(line.Move) + ": " + ow.Thousands(line.Visits) + " visits, " + Percent(line.WinRate) + " " + line.Game.String()
+}
+
+func (result *MCTSResult) String() string {
+	return ow.Thousands(result.Playouts) + " playouts, " + ow.Thousands(len(result.Lines)) + " moves | " +
+		strconv.FormatFloat(result.Elapsed, 'f', 2, 64) + " sec."
+}
+
+// win rate as a percentage
+func Percent(rate float64) string {
+	return strconv.FormatFloat(100*rate, 'f', 1, 64) + "%"
+}
+
+// a node of a goroutine's tree
+type mctsNode struct {
+	move     int8
+	visits   int
+	wins     float64 // for the side that moved here
+	children []*mctsNode
+	untried  []int8
+	parent   *mctsNode
+	position *mech.Position // from the perspective of the side to move
+	terminal bool
+	value    float64 // terminal outcome for the side to move
+}
+
+// a goroutine's search
+type mcts struct {
+	rng     *rand.Rand
+	root    *mctsNode
+	history []*mech.Position // root game up to the cursor, for cycles
+}
+
+////////////////////////////////////////////////////////////////
+// SEARCH
+////////////////////////////////////////////////////////////////
+
+// Monte Carlo tree search from the current position of the game; root parallelism with the given goroutines
+func MonteCarlo(ctx context.Context, game *mech.Game, goroutines int, limits Limits) *MCTSResult {
+	begin := time.Now().UTC().UnixNano()
+	limits = limits.deterministic()
+	ctx, cancel := withDuration(ctx, limits.Duration)
+	defer cancel()
+
+	playouts := limits.Nodes
+	if playouts <= 0 {
+		playouts = Playouts
+	}
+	goroutines = ow.Max(1, ow.Min(goroutines, playouts))
+	history := game.Positions[:game.Cursor+1]
+
+	searches := make([]*mcts, goroutines)
+	var wg sync.WaitGroup
+	for i := range searches {
+		seed := time.Now().UTC().UnixNano() + int64(i)
+		if Deterministic {
+			seed = ow.Seed + int64(i)
+		}
+		search := &mcts{rng: rand.New(rand.NewSource(seed)), history: history}
+		search.root = search.newNode(nil, NO_MOVE, game.Current())
+		searches[i] = search
+
+		// the first goroutines take the remainder
+		n := playouts / goroutines
+		if i < playouts%goroutines {
+			n++
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			search.run(ctx, n)
+		}()
+	}
+	wg.Wait()
+
+	result := merge(game, searches)
+	result.Elapsed = float64(time.Now().UTC().UnixNano()-begin) / ow.GIGA64F
+	fmt.Println("mcts:", result)
+	return result
+}
+
+// grow the tree by n playouts or until the context is done
+func (search *mcts) run(ctx context.Context, n int) {
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		// selection
+		node := search.root
+		for !node.terminal && len(node.untried) == 0 {
+			node = search.uct(node)
+		}
+
+		// expansion
+		if !node.terminal {
+			k := search.rng.Intn(len(node.untried))
+			move := node.untried[k]
+			node.untried[k] = node.untried[len(node.untried)-1]
+			node.untried = node.untried[:len(node.untried)-1]
+
+			child := search.newNode(node, move, node.position.Move(move))
+			node.children = append(node.children, child)
+			node = child
+		}
+
+		// playout and backpropagation; value for the side to move at node
+		value := node.value
+		if !node.terminal {
+			value = search.playout(node)
+		}
+		for ; node != nil; node = node.parent {
+			node.visits++
+			node.wins += 1 - value
+			value = 1 - value
+		}
+	}
+}
+
+// child with the best UCT value
+func (search *mcts) uct(node *mctsNode) *mctsNode {
+	logVisits := math.Log(float64(node.visits))
+	var best *mctsNode
+	bestValue := math.Inf(-1)
+	for _, child := range node.children {
+		value := child.wins/float64(child.visits) + Exploration*math.Sqrt(logVisits/float64(child.visits))
+		if value > bestValue {
+			best, bestValue = child, value
+		}
+	}
+	return best
+}
+
+// a tree node with its moves or terminal outcome
+func (search *mcts) newNode(parent *mctsNode, move int8, position *mech.Position) *mctsNode {
+	node := &mctsNode{move: move, parent: parent, position: position}
+	if value, ok := search.outcome(position, search.cycle(node)); ok {
+		node.terminal, node.value = true, value
+		return node
+	}
+	node.untried = playoutMoves(position)
+	return node
+}
+
+// play to the end from a node; outcome for the side to move at the node
+func (search *mcts) playout(node *mctsNode) float64 {
+	// boards since the last capture: only those can repeat; the tree path, then the root game, as cycle()
+	stones := node.position.Stones()
+	var boards []mech.Board
+	n := node
+	for ; n != nil && n.position.Stones() == stones; n = n.parent {
+		boards = append(boards, n.position.Board)
+	}
+	if n == nil {
+		// the root is the last position of its game
+		for i := len(search.history) - 2; i >= 0 && search.history[i].Stones() == stones; i-- {
+			boards = append(boards, search.history[i].Board)
+		}
+	}
+
+	position := node.position
+	for ply := 0; ; ply++ {
+		moves := playoutMoves(position)
+		move := moves[search.rng.Intn(len(moves))]
+		if Playout == GREEDY {
+			move = greedy(position, moves, move)
+		}
+		position = position.Move(move)
+
+		if position.Stones() != stones {
+			stones = position.Stones()
+			boards = boards[:0]
+		}
+		cycle := false
+		for _, board := range boards {
+			if board == position.Board {
+				cycle = true
+				break
+			}
+		}
+		boards = append(boards, position.Board)
+
+		value, ok := search.outcome(position, cycle)
+		if !ok && ply >= MCTS_PLIES {
+			value, ok = split(position), true
+		}
+		if ok {
+			// the side to move alternates
+			if ow.Even(ply) {
+				return 1 - value
+			}
+			return value
+		}
+	}
+}
+
+// outcome for the side to move; false if the game goes on
+func (search *mcts) outcome(position *mech.Position, cycle bool) (float64, bool) {
+	switch {
+	case position.Scores[0] >= WINNING:
+		return 1, true
+	case position.Scores[1] >= WINNING:
+		return 0, true
+	case position.Starved() || cycle:
+		return split(position), true
+	}
+
+	// exact score of the stones on the board, v. negaMax
+	rank := position.Rank()
+	if !db.Covers(rank) {
+		return 0, false
+	}
+	if score, ok := db.GetScore(rank); ok {
+		share := (position.Stones() + score) / 2
+		return compare(position.Scores[0]+share, position.Scores[1]+position.Stones()-share), true
+	}
+	return 0, false
+}
+
+// does the node's board repeat a board of its path or of the root game?
+func (search *mcts) cycle(node *mctsNode) bool {
+	for n := node.parent; n != nil; n = n.parent {
+		if n.position.EQ(node.position) {
+			return true
+		}
+	}
+	if node.parent == nil {
+		// the root itself, as Game.Cycle()
+		for _, position := range search.history[:len(search.history)-1] {
+			if position.EQ(node.position) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, position := range search.history {
+		if position.EQ(node.position) {
+			return true
+		}
+	}
+	return false
+}
+
+// outcome when each player takes the stones on her side
+func split(position *mech.Position) float64 {
+	return compare(position.Scores[0]+position.SouthStones(), position.Scores[1]+position.NorthStones())
+}
+
+// 1 for a win, ½ for a draw, 0 for a loss
+func compare(mine, theirs int8) float64 {
+	switch {
+	case mine > theirs:
+		return 1
+	case mine < theirs:
+		return 0
+	default:
+		return 0.5
+	}
+}
+
+// moves that feed the opponent, or all moves if none does; cheaper than LegalMoves(), which ranks the successors
+func playoutMoves(position *mech.Position) []int8 {
+	var feeding, all []int8
+	for move := mech.SOUTHLEFT; move <= mech.SOUTHRIGHT; move++ {
+		if position.Board[move] == 0 {
+			continue
+		}
+		all = append(all, move)
+		if !position.Move(move).Starved() {
+			feeding = append(feeding, move)
+		}
+	}
+	if len(feeding) == 0 {
+		return all
+	}
+	return feeding
+}
+
+// the biggest capture, if any; else the random move
+func greedy(position *mech.Position, moves []int8, random int8) int8 {
+	best, most := random, int8(0)
+	for _, move := range moves {
+		// the mover's captures are the successor's opponent's
+		if capture := position.Move(move).Scores[1] - position.Scores[0]; capture > most {
+			best, most = move, capture
+		}
+	}
+	return best
+}
+
+////////////////////////////////////////////////////////////////
+// RESULT
+////////////////////////////////////////////////////////////////
+
+// sum up the root moves of all trees; the continuation comes from the tree that visited the move most
+func merge(game *mech.Game, searches []*mcts) *MCTSResult {
+	result := new(MCTSResult)
+	lines := make(map[int8]*MCTSLine)
+	wins := make(map[int8]float64)
+	deepest := make(map[int8]*mctsNode)
+	for _, search := range searches {
+		result.Playouts += search.root.visits
+		for _, child := range search.root.children {
+			line, ok := lines[child.move]
+			if !ok {
+				line = &MCTSLine{Move: child.move}
+				lines[child.move] = line
+			}
+			line.Visits += child.visits
+			wins[child.move] += child.wins
+			if best, ok := deepest[child.move]; !ok || child.visits > best.visits {
+				deepest[child.move] = child
+			}
+		}
+	}
+
+	for move, line := range lines {
+		line.WinRate = wins[move] / float64(line.Visits)
+		line.Game = continuation(game, deepest[move])
+		result.Lines = append(result.Lines, line)
+	}
+	sort.Slice(result.Lines, func(i, j int) bool {
+		if result.Lines[i].Visits != result.Lines[j].Visits {
+			return result.Lines[i].Visits > result.Lines[j].Visits
+		}
+		return result.Lines[i].Move < result.Lines[j].Move
+	})
+	return result
+}
+
+// the game through a root move, following the most visited children; cursor at the root
+func continuation(game *mech.Game, node *mctsNode) *mech.Game {
+	r := game.Move(node.move)
+	for len(node.children) > 0 && !r.GameOver() {
+		best := node.children[0]
+		for _, child := range node.children[1:] {
+			if child.visits > best.visits {
+				best = child
+			}
+		}
+		node = best
+		r = r.Move(node.move)
+	}
+	r.Cursor = game.Cursor
+	return r
+}
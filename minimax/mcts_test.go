@@ -0,0 +1,74 @@
+package minimax
+
+import (
+	"context"
+	"reflect"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"testing"
+)
+
+// the playouts of all goroutines add up to the node limit, and so do the visits of the root moves
+func TestMonteCarloPlayouts(t *testing.T) {
+	game := mech.StringToGame(INITIAL)
+	moves := len(game.Current().LegalMoves().Moves)
+	for _, c := range []struct{ goroutines, playouts int }{{1, 1000}, {3, 1000}, {4, 1000}, {8, 3}} {
+		result := MonteCarlo(context.Background(), game, c.goroutines, Limits{Nodes: c.playouts})
+		if result.Playouts != c.playouts {
+			t.Errorf("%d goroutines: %d playouts; want %d", c.goroutines, result.Playouts, c.playouts)
+		}
+
+		visits := 0
+		for i, line := range result.Lines {
+			visits += line.Visits
+			if line.WinRate < 0 || line.WinRate > 1 {
+				t.Errorf("%d goroutines: %v: win rate out of range", c.goroutines, line)
+			}
+			if i > 0 && line.Visits > result.Lines[i-1].Visits {
+				t.Errorf("%d goroutines: %v: more visited than %v", c.goroutines, line, result.Lines[i-1])
+			}
+			if line.Game.Cursor != game.Cursor || line.Game.Moves[game.Cursor] != line.Move {
+				t.Errorf("%d goroutines: %v: continuation %v", c.goroutines, line, line.Game)
+			}
+		}
+		if visits != c.playouts {
+			t.Errorf("%d goroutines: %d visits; want %d", c.goroutines, visits, c.playouts)
+		}
+		if c.playouts >= 1000 && len(result.Lines) != moves {
+			t.Errorf("%d goroutines: %d moves tried; want %d", c.goroutines, len(result.Lines), moves)
+		}
+	}
+}
+
+// deterministic mode: the same statistics on every run
+func TestMonteCarloDeterministic(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+	Deterministic = true
+
+	game := mech.StringToGame("/40449128654")
+	stats := func() map[int8][2]float64 {
+		r := map[int8][2]float64{}
+		for _, line := range MonteCarlo(context.Background(), game, 2, Limits{Nodes: 500}).Lines {
+			r[line.Move] = [2]float64{float64(line.Visits), line.WinRate}
+		}
+		return r
+	}
+	if first, second := stats(), stats(); !reflect.DeepEqual(first, second) {
+		t.Errorf("%v\n%v", first, second)
+	}
+}
+
+// positions of 47 stones, not covered by the database, are played out rather than looked up
+func TestMonteCarloLevel47(t *testing.T) {
+	loserDatabase(t)
+	rank := ow.LevelUpperLimits[46] + 1
+	for mech.StringToGame("/" + strconv.FormatInt(rank, 10)).GameOver() {
+		rank++
+	}
+	game := mech.StringToGame("/" + strconv.FormatInt(rank, 10))
+
+	if result := MonteCarlo(context.Background(), game, 1, Limits{Nodes: 100}); result.Playouts != 100 {
+		t.Errorf("%v: %d playouts", game, result.Playouts)
+	}
+}
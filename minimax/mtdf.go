@@ -0,0 +1,89 @@
+package minimax
+
+// MTD(f): a sequence of zero-window negamax searches converging on the minimax score.
+// An alternative driver to the parallel aspiration; single-threaded.
+//
+// Each null-window search bounds the root score from one side;
+// *TT.save() intersects the bounds, until the root interval collapses to a score;
+// a last search with the window around the score yields the principal variation.
+// Oware's narrow integer score range makes for few passes.
+
+import (
+	"context"
+	"fmt"
+	"sankofa/mech"
+	"sankofa/ow"
+)
+
+// search drivers of the iterative deepener
+const (
+	ASPIRATION = "aspiration"
+	MTDF       = "mtdf"
+)
+
+// search driver used by Explore()
+var Search = ASPIRATION
+
+// first guess: the score found by the previous iteration, or the evaluation
+func (tt *TT) guess() int8 {
+	game := tt.Game()
+	if tt.old != nil {
+		interval := tt.old.Interval(game.Current().Rank())
+		if interval != nil && interval.Scored() {
+			return interval.Score()
+		}
+	}
+	return tt.evaluate(game)
+}
+
+// Plaat's MTD(f) for a given depth; result in transposition table
+func (tt *TT) MTDF(ctx context.Context, depth int) *TT {
+	game := tt.Game()
+	level := ow.Level(game.Current().Rank())
+
+	lower, upper := -level, level
+	g := ow.Max(lower, ow.Min(upper, tt.guess()))
+	ow.Log(game, "guess:", g, "depth:", depth)
+
+	var verdict int8
+	var continuation *mech.Game
+	for lower < upper && ctx.Err() == nil {
+		β := g
+		if g == lower {
+			β = g + 1
+		}
+
+		g, verdict, continuation = tt.NegaMax(ctx, game, β-1, β, depth)
+		tt.incPasses()
+		if g < β {
+			upper = g
+		} else {
+			lower = g
+		}
+		fmt.Println("MTD(f): β:", β, "⇢", g, ow.Thousands(lower, upper))
+	}
+
+	// the null-window passes return fail-high or fail-low lines:
+	// once converged, the window around the score yields the principal variation;
+	// its nodes are searched again, the others answered by the passes' bounds
+	if lower == upper && ctx.Err() == nil {
+		tt.principal = true
+		g, verdict, continuation = tt.NegaMax(ctx, game, g-1, g+1, depth)
+		tt.principal = false
+		tt.incPasses()
+		fmt.Println("MTD(f): principal variation ⇢", g)
+	}
+
+	// the continuation of the last pass
+	if continuation != nil {
+		continuation.Cursor = game.Cursor
+		fmt.Println("continuation ⇢", continuation, "score±heuristic:", mech.VerdictToString(verdict), g)
+
+		tt.mutex.Lock()
+		tt._setGame(continuation)
+		tt.found = true
+		tt.mutex.Unlock()
+	}
+
+	return tt
+}
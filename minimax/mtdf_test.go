@@ -0,0 +1,57 @@
+package minimax
+
+import (
+	"context"
+	"sankofa/mech"
+	"strconv"
+	"testing"
+)
+
+// fixed-depth searches of the deterministic trails, one result per trail
+func fixedDepth(driver string, pvs bool, goroutines, depth int) []*SearchResult {
+	defer func(search string, p bool) { Search, PVS = search, p }(Search, PVS)
+	Search, PVS = driver, pvs
+
+	var results []*SearchResult
+	for _, trail := range deterministicTrails {
+		results = append(results, search(trail, goroutines, Limits{Depth: depth}))
+	}
+	return results
+}
+
+// score of a root move for the side to move, by a full-window search of its successor one ply less deep
+func moveScore(trail string, move int8, depth int) int8 {
+	defer func(search string, pvs bool) { Search, PVS = search, pvs }(Search, PVS)
+	Search, PVS = ASPIRATION, false
+
+	game := mech.StringToGame(trail)
+	capture := game.Current().LegalMoves().Score[move]
+	result := NewTT(game.Move(move)).ExploreLimits(context.Background(), 1, Limits{Depth: depth - 1}).Result()
+	return capture - result.Interval.Score()
+}
+
+// the same root interval as the reference results, and a move as good as theirs;
+// moves of equal scores are told apart by the move order, which the drivers need not share
+func sameResults(t *testing.T, name string, depth int, got, want []*SearchResult) {
+	t.Helper()
+	for i, trail := range deterministicTrails {
+		if got[i].Interval.String() != want[i].Interval.String() {
+			t.Errorf("%s: %s: %v; want %v", name, trail, &got[i].SearchInfo, &want[i].SearchInfo)
+		} else if got[i].Move != want[i].Move {
+			score := want[i].Interval.Score()
+			if moveScore(trail, got[i].Move, depth) != score || moveScore(trail, want[i].Move, depth) != score {
+				t.Errorf("%s: %s: move %s; want %s", name, trail, mech.MoveToString(got[i].Move), mech.MoveToString(want[i].Move))
+			}
+		}
+	}
+}
+
+// MTD(f)'s zero-window passes reach the root score and move of plain aspiration
+func TestMTDF(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+	Deterministic = true
+
+	for depth := 3; depth <= 7; depth++ {
+		sameResults(t, "MTD(f), depth "+strconv.Itoa(depth), depth, fixedDepth(MTDF, false, 1, depth), fixedDepth(ASPIRATION, false, 1, depth))
+	}
+}
@@ -0,0 +1,106 @@
+package minimax
+
+// multi-PV: exact scores and continuations for the best root moves
+//
+// Explore() bounds most root moves only, e.g., ≥3, since it cuts off whatever cannot beat the best move.
+// MultiPV() takes the last completed iteration and searches the root moves one by one with the full window:
+//   - at the same depth, so that the scores can be compared with the root's score
+//   - best upper bound first, as known from Explore() for the root's history
+//   - until n moves are exact and no other move can beat the n-th best, or until a limit is reached
+//
+// The searches run on a table of their own: the explored *TT is left as it is.
+// Only the duration and node limits apply, the latter counted afresh; moves not finished within them are left out.
+// Without them, all n moves are searched: the depth is fixed.
+
+import (
+	"context"
+	"fmt"
+	"sankofa/mech"
+	"sankofa/ow"
+	"sort"
+)
+
+// a root move with its exact score and continuation
+type Line struct {
+	Move    int8       // house moved, from the perspective of the side to move
+	Score   int8       // for the side to move, as the root's score
+	Verdict int8       // for the side to move
+	Game    *mech.Game // continuation; cursor at the root
+}
+
+func (line *Line) String() string {
+	return mech.MoveToString(line.Move) + ": " + mech.VerdictToString(line.Verdict) + ow.Thousands(line.Score) + " " + line.Game.String()
+}
+
+// exact lines for the best n root moves, best first; all moves if n ≤ 0
+func (tt *TT) MultiPV(ctx context.Context, n int, limits Limits) []*Line {
+	root := tt.Game()
+	rank := root.Current().Rank()
+	depth := tt.Depth()
+	if root.GameOver() || depth < 1 {
+		return nil
+	}
+	legalMoves := tt.LegalMoves(rank)
+	if n <= 0 || n > len(legalMoves.Next) {
+		n = len(legalMoves.Next)
+	}
+
+	// own table and fresh timer; the results of Explore() for move ordering
+	pv := tt.fork().setGame(root)
+	limits = limits.deterministic()
+	ctx, cancel := withDuration(ctx, limits.Duration)
+	defer cancel()
+	pv.setDeepener(ctx)
+	pv.cumVisited = 0
+	pv.limits = Limits{Duration: limits.Duration, Nodes: limits.Nodes}
+
+	// upper bounds of the moves; only from intervals that apply to the root's history, v. history.go
+	type candidate struct {
+		move  int8
+		upper int8
+	}
+	candidates := make([]candidate, 0, len(legalMoves.Next))
+	for move, next := range legalMoves.Next {
+		upper := legalMoves.Score[move] + ow.Level(next)
+		if interval := tt.Interval(next); interval != nil && interval.applies(root.Move(move)) {
+			upper = legalMoves.Score[move] - interval.low
+		}
+		candidates = append(candidates, candidate{move, upper})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].upper != candidates[j].upper {
+			return candidates[i].upper > candidates[j].upper
+		}
+		return candidates[i].move < candidates[j].move
+	})
+
+	lines := make([]*Line, 0, n)
+	for _, c := range candidates {
+		// the n-th best line cannot be beaten
+		if len(lines) >= n && lines[n-1].Score >= c.upper {
+			break
+		}
+
+		mv := root.Move(c.move)
+		lv := ow.Level(mv.Current().Rank())
+		s, v, g, _ := pv.negaMax(pv.deepener, mv, -lv, lv, depth-1, 0)
+		if pv.DeepenerAborted() {
+			ow.Log("multi-PV: time limit:", len(lines), "lines")
+			break
+		}
+
+		g.Cursor = root.Cursor
+		lines = append(lines, &Line{c.move, legalMoves.Score[c.move] - s, mech.ReverseVerdict(v), g})
+		sort.SliceStable(lines, func(i, j int) bool {
+			return lines[i].Score > lines[j].Score
+		})
+	}
+
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	for _, line := range lines {
+		fmt.Println("multi-PV ⇢", line)
+	}
+	return lines
+}
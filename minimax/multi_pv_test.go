@@ -0,0 +1,37 @@
+package minimax
+
+import (
+	"context"
+	"sankofa/mech"
+	"testing"
+)
+
+// multi-PV lines are sorted, the first has the root's score, and each scores its move as a search of the move alone
+func TestMultiPV(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+	Deterministic = true
+
+	const depth = 5
+	for _, trail := range deterministicTrails {
+		game := mech.StringToGame(trail)
+		tt := NewTT(game).ExploreLimits(context.Background(), 1, Limits{Depth: depth})
+		lines := tt.MultiPV(context.Background(), 0, Limits{})
+		if len(lines) != len(game.Current().LegalMoves().Moves) {
+			t.Errorf("%s: %d lines; want all moves", trail, len(lines))
+		}
+		for i, line := range lines {
+			if i == 0 && line.Score != tt.Result().Interval.Score() {
+				t.Errorf("%s: best line %v; root score %v", trail, line, tt.Result().Interval)
+			}
+			if i > 0 && line.Score > lines[i-1].Score {
+				t.Errorf("%s: %v after %v", trail, line, lines[i-1])
+			}
+			if line.Game.Cursor != game.Cursor || line.Game.Moves[game.Cursor] != line.Move {
+				t.Errorf("%s: %v: continuation %v", trail, line, line.Game)
+			}
+			if score := moveScore(trail, line.Move, depth); line.Score != score {
+				t.Errorf("%s: %v; single-PV score %d", trail, line, score)
+			}
+		}
+	}
+}
@@ -4,11 +4,19 @@
 //
 // Algorithm:
 //   - parallel aspiration on discrete quartiles
+//   - alternative search drivers: MTD(f), lazy SMP
 //   - iterative deepener
 //   - negamax
-//   - transposition table (previous iteration) used for move ordering
+//   - move ordering: previous iteration or cache, captures, killer moves per ply, history heuristic
+//   - optional cache of earlier searches' results
+//   - optional opening book, consulted before searching
 //   - fail-soft α—β pruning
-//   - simple heuristic score
+//   - quiescence search on captures and forced feeding, v. Quiescence
+//   - optional principal variation search (NegaScout)
+//   - pluggable heuristic score, v. Evaluator
+//   - Monte Carlo tree search (UCT) as an alternative analyser, v. MonteCarlo
+//   - proof-number search for win/loss verdicts, with the database as terminal knowledge, v. Prove
+//   - optional recording of the searched tree, exported as JSON or Graphviz DOT, v. SearchTree
 //
 // # DESIGN, TACTICS AND HACKS
 //
@@ -20,16 +28,20 @@
 //   - negamax returns the *Game steps corresponding to the found score
 //   - parallel aspiration has a bit transaction block that uses the non-locking inner methods
 //   - WARNING negamax may return an empty or a truncated continuation when it returns a score from *TT
+//   - scores relying on the history of a cycle are only reused for the same history, v. history.go
 //   - a DB read operation is about one order of magnitude slower than the trivial heuristic we employ.
 //     DB scores are only used for the leaves, since we are interested in the game continuation discovered by α—β.
 //     a good compromise is to limit the database to the lower end-game levels e.g., up to 24.
 //
 // # CALL STACK
 //
-// Explore ⇢ Aspiration ⇢ Worker ⇢ NegaMax
+//   - Explore ⇢ Aspiration ⇢ Worker ⇢ NegaMax
+//   - Explore ⇢ MTDF ⇢ NegaMax
+//   - Explore ⇢ LazySMP ⇢ helper ⇢ negaMax
 package minimax
 
 import (
+	"context"
 	"fmt"
 	"sankofa/db"
 	"sankofa/mech"
@@ -39,10 +51,21 @@ import (
 // complete search tree; disables cutoff
 var Complete bool
 
+// principal variation search (NegaScout): null windows for all but the first killer move
+var PVS bool
+
 // fail-soft NegaMax with α—β pruning and killer-move heuristic;
 // modifies the input *TT and returns the game continuation;
-// score and verdict are used internally.
-func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict int8, continuation *mech.Game) {
+// score and verdict are used internally;
+// returns early with the evaluation when the context is cancelled.
+func (tt *TT) NegaMax(ctx context.Context, game *mech.Game, α, β int8, depth int) (score, verdict int8, continuation *mech.Game) {
+	score, verdict, continuation, _ = tt.negaMax(ctx, game, α, β, depth, 0)
+	return
+}
+
+// NegaMax for a given lazy-SMP helper; helper 0 is the main search;
+// reach: the earliest position of the game that a cycle below relied on, NO_CYCLE if none, v. history.go
+func (tt *TT) negaMax(ctx context.Context, game *mech.Game, α, β int8, depth, helper int) (score, verdict int8, continuation *mech.Game, reach int) {
 	// sanity check
 	if β < α {
 		ow.Panic("α=", α, "> β=", β, game)
@@ -51,9 +74,9 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 	// visit this node
 	tt.incVisited()
 
-	// update base and depth
+	// update base and depth; quiescence extends below the bottom
 	tt.setDepth(depth)
-	tt.setBase(depth)
+	tt.setBase(ow.Max(0, depth))
 	ow.Log(game, "depth:", depth, "TT: base:", tt.Base(), "depth:", tt.Depth())
 
 	// pre-compute some useful values
@@ -61,9 +84,23 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 	rank := position.Rank()
 	legalMoves := tt.LegalMoves(rank) // for tracing
 	verdict = game.Current().Verdict()
+	repetition := game.Repetition()
+
+	// terminal positions are not looked up, v. the base conditions
+	var stored, cached *Interval
+	var draft int
+	if !position.FinalScore() && !position.Starved() && repetition < 0 {
+		stored = tt.lookup(game)
+		cached, draft = tt.cached(game, rank, α, β, depth)
+	}
 
 	ow.Log(game, "⇢visit: game:", game, "@", game.Cursor, "position:", position, "legal moves:", legalMoves, "α:", α, "β:", β, "depth:", depth)
 
+	// record the node, if the tree is recorded; v. search_tree.go
+	node := tt.enter(game, α, β, depth)
+	source, cut := SOURCE_SEARCH, ""
+	defer tt.leave(node, &score, &source, &cut)
+
 	trace(">>", game, game.Last().Score()+game.BeforeLast().Score(), α, β, legalMoves)
 
 	////////////////////////////////////////////////////////////////
@@ -77,12 +114,13 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 
 		ow.Log(game, "⇠over:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
 		tt.incOver()
+		source = SOURCE_TERMINAL
 		trace("<< over", game, score, α, β, legalMoves)
 
 		// save score to the transposition table
-		tt.save(rank, α, β, score, verdict)
+		tt.save(rank, α, β, score, verdict, depth, path{})
 
-		return score, verdict, game
+		return score, verdict, game, NO_CYCLE
 	case position.Starved():
 		// starved (terminal)
 		// cannot use game.Last().Score(), as this is not set if there is no preceding move
@@ -91,50 +129,81 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 
 		ow.Log(game, "⇠starved:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
 		tt.incOver()
+		source = SOURCE_TERMINAL
 		trace("<< starved", game, score, α, β, legalMoves)
 
 		// save score to the transposition table
-		tt.save(rank, α, β, score, verdict)
+		tt.save(rank, α, β, score, verdict, depth, path{})
 
-		return score, mech.LOSS, game
-	case game.Cycle():
+		return score, mech.LOSS, game, NO_CYCLE
+	case repetition >= 0:
 		// cycle (terminal)
 		score := game.Capture()
 		ow.Log(game, "⇠cycle:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
 		tt.incOver()
+		source = SOURCE_TERMINAL
 		trace("<< cycle", game, score, α, β, legalMoves)
 
-		// save score to the transposition table
-		tt.save(rank, α, β, score, verdict)
+		// save score to the transposition table; it relies on the repeated position
+		tt.save(rank, α, β, score, verdict, depth, pathOf(game, repetition))
 
-		return score, verdict, game
-	case tt.Known(rank) && tt.Interval(rank).Scored():
-		i := tt.Interval(rank)
-		score, verdict := i.Score(), i.Verdict()
-		// counter incremented by *TT.Interval() call
+		return score, verdict, game, repetition
+	case stored != nil && stored.Scored() && !(tt.principal && β-α > 1):
+		score, verdict := stored.Score(), stored.Verdict()
+		// counter incremented by *TT.lookup() call
+		tt.incHits()
 		ow.Log(game, "⇠TT:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
-		return score, verdict, game
-	case depth == 0:
+		source = SOURCE_TT
+		return score, verdict, game, stored.reach(game)
+	case !Complete && stored != nil && bounded(stored, α, β):
+		// a bound from an earlier search, e.g., an MTD(f) pass, falls outside the window
+		score, _ := stored.Bound(α, β)
+		tt.incHits()
+		ow.Log(game, "⇠TT bound:", game, "|", game.Current().Board, "score:", score, "interval:", stored)
+		source, cut = SOURCE_TT, CUT_BOUND
+		trace("<< bound", game, score, α, β, legalMoves)
+		return score, stored.Verdict(), game, stored.reach(game)
+	case cached != nil:
+		// result of an earlier search, at least as deep
+		score, _ := cached.Bound(α, β)
+		if cached.Scored() {
+			score = cached.Score()
+		}
+		// the earlier search reached the bottom below this node
+		tt.setBase(depth - draft)
+		ow.Log(game, "⇠cache:", game, "|", game.Current().Board, "score:", score, "interval:", cached, "draft:", draft)
+		source = SOURCE_CACHE
+		trace("<< cache", game, score, α, β, legalMoves)
+		return score, cached.Verdict(), game, cached.reach(game)
+	case ctx.Err() != nil:
+		// stop processing; neither recurse nor extend the bottom
+		score := tt.evaluate(game)
+		ow.Log(game, "⇠cancelled:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
+		source = SOURCE_CANCELLED
+		trace("<< done", game, score, α, β, legalMoves)
+		return score, verdict, game, NO_CYCLE
+	case depth <= 0:
 		// reached recursion depth limit
 		// search for a score in the database
 		score, ini := db.GetScore(rank)
 		if ini {
 			ow.Log(game, "⇠bottom+database:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
 			tt.incDatabase()
+			source = SOURCE_DATABASE
+		} else if tt.noisy(game, legalMoves, depth) {
+			// quiescence: continue with the captures below
+			tt.incQuiescence()
+			source = SOURCE_QUIESCENCE
+			break
 		} else {
 			// evaluate score using a heuristic
-			score = game.Heuristic()
+			score = tt.evaluate(game)
 			ow.Log(game, "⇠bottom+heuristic:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
 			tt.incHeuristic()
+			source = SOURCE_HEURISTIC
 		}
 		trace("<< bottom", game, score, α, β, legalMoves)
-		return score, verdict, game
-	case tt.IterationAborted() || tt.DeepenerAborted():
-		// stop processing
-		score := game.Heuristic()
-		ow.Log(game, "⇠cancelled:", game, "|", game.Current().Board, "score:", score, "verdict:", verdict)
-		trace("<< done", game, score, α, β, legalMoves)
-		return score, verdict, game
+		return score, verdict, game, NO_CYCLE
 	}
 
 	////////////////////////////////////////////////////////////////
@@ -144,6 +213,21 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 	// "game" imutable
 	bestGame := game.Clone()
 	bestScore := ow.MININT8
+	reach = NO_CYCLE
+	exposed := false // a move without capture was searched
+
+	// quiescence: the side to move may stand pat, unless forced to feed
+	quiescence := depth <= 0
+	forced := position.Reverse().Starved()
+	if quiescence && !forced {
+		bestScore = tt.evaluate(game)
+		ow.Log(game, "stand pat:", bestScore)
+		if bestScore >= β {
+			source, cut = SOURCE_HEURISTIC, CUT_STANDPAT
+			trace("<< pat", game, bestScore, α, β, legalMoves)
+			return bestScore, verdict, game, NO_CYCLE
+		}
+	}
 
 	// side
 	side := "♟︎"
@@ -151,27 +235,50 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 		side = "♙"
 	}
 
-	killerMoves := tt.KillerMoves(rank)
+	killerMoves := tt.helperMoves(game, helper)
+	searched := 0 // moves searched so far
 	for _, move := range killerMoves {
+		if quiescence && !forced && legalMoves.Score[move] == 0 {
+			// quiet move
+			continue
+		}
+		searched++
 		ow.Log(game, "rank:", rank, "killer move:", mech.MoveToString(move), "⇢ successor:", legalMoves.Next[move], ", captures:", legalMoves.Score[move])
 		ow.Log(game, "α:", α, ", best score:", bestScore, ", β:", β)
 
 		var s, v int8
 		var g *mech.Game
+		var r int
 
-		// trim α—β to plausible score range for this level
+		// the successor's window: t = c - s ∈ (α, β) ⟺ s ∈ (c-β, c-α);
+		// trimmed to the plausible score range of its level
 		mv := game.Move(move)
 		lv := ow.Level(mv.Current().Rank())
-		a := ow.Max(-lv, ow.Min(lv, α))
-		b := ow.Max(-lv, ow.Min(lv, β))
-
-		if Complete || tt.Depth()-depth <= 1 {
+		c := legalMoves.Score[move]
+		a := ow.Max(-lv, ow.Min(lv, c-β))
+		b := ow.Max(-lv, ow.Min(lv, c-α))
+		// according to Marsland: α ⇢ ow.Max(α, bestScore)
+		m := ow.Max(-lv, ow.Min(lv, c-ow.Max(α, ow.Min(β, bestScore))))
+
+		switch {
+		case Complete || tt.Depth()-depth <= 1:
 			// ignore cuts when traversing the entire tree
-			s, v, g = tt.NegaMax(mv, -b, -a, depth-1)
-		} else {
-			// according to Marsland: -ow.Max(α, bestScore)
-			s, v, g = tt.NegaMax(mv, -b, -ow.Max(a, ow.Min(b, bestScore)), depth-1)
+			s, v, g, r = tt.negaMax(ctx, mv, a, b, depth-1, helper)
+		case PVS && searched > 1:
+			// null window after the first searched move: is the move better than the best so far?
+			s, v, g, r = tt.scout(ctx, mv, c, ow.Max(α, ow.Min(β, bestScore)), lv, depth-1, helper)
+			if t := c - s; t > ow.Max(α, bestScore) && t < β {
+				// fail-high: re-search with the full window
+				tt.incResearched()
+				reach = ow.Min(reach, r)
+				s, v, g, r = tt.negaMax(ctx, mv, a, m, depth-1, helper)
+			}
+		default:
+			s, v, g, r = tt.negaMax(ctx, mv, a, m, depth-1, helper)
 		}
+		// every searched move bears on the score
+		reach = ow.Min(reach, r)
+		exposed = exposed || c == 0
 
 		t := legalMoves.Score[move] - s // best score candidate
 		if t > bestScore {
@@ -202,7 +309,11 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 							fmt.Println(side + " || cut " + bestGame.String() + " " + scoreString(game.Cursor, bestScore) + " <= " + αβString(game.Cursor, α, β))
 						}
 					}
-					tt.incCutOff()
+					tt.cutOffBy(game, move, legalMoves.Score[move] > 0, searched == 1, depth)
+					cut = CUT_BETA
+					if verdict == mech.WIN {
+						cut = CUT_WIN
+					}
 
 					// "break" realizes the cut off
 					break
@@ -222,9 +333,28 @@ func (tt *TT) NegaMax(game *mech.Game, α, β int8, depth int) (score, verdict i
 		ow.Panic("recursion on a final position")
 	}
 
-	// save score to the transposition table
-	tt.save(rank, α, β, bestScore, verdict)
+	// the positions since the last capture might have been reached below, v. history.go
+	path := pathOf(game, reach)
+	if exposed {
+		path = levelPathOf(game)
+		reach = ow.Min(reach, game.Cursor-path.plies)
+	}
+
+	// save score to the transposition table;
+	// stopped searches are discarded, whichever the helper: their scores stem from truncated subtrees;
+	// quiescence scores are not, like those of the bottom: the TT answers regardless of the remaining depth
+	if ctx.Err() == nil && !quiescence {
+		tt.save(rank, α, β, bestScore, verdict, depth, path)
+	}
 
 	trace("<< nmax", bestGame, bestScore, α, β, legalMoves)
-	return bestScore, verdict, bestGame
+	return bestScore, verdict, bestGame, reach
+}
+
+// null-window search of a successor reached by a move capturing c stones:
+// the move beats α iff the successor's score is below c-α;
+// the window is trimmed to the successor's level lv.
+func (tt *TT) scout(ctx context.Context, game *mech.Game, c, α, lv int8, depth, helper int) (score, verdict int8, continuation *mech.Game, reach int) {
+	x := ow.Max(-lv+1, ow.Min(lv, c-α))
+	return tt.negaMax(ctx, game, x-1, x, depth, helper)
 }
@@ -0,0 +1,34 @@
+package minimax
+
+import (
+	"strconv"
+	"testing"
+)
+
+// principal variation search changes the searched nodes, not the root score or move
+func TestPVS(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+	Deterministic = true
+
+	for depth := 3; depth <= 7; depth++ {
+		want := fixedDepth(ASPIRATION, false, 1, depth)
+		for _, driver := range []string{ASPIRATION, MTDF} {
+			sameResults(t, driver+" with PVS, depth "+strconv.Itoa(depth), depth, fixedDepth(driver, true, 1, depth), want)
+		}
+	}
+}
+
+// the windows of the successors are offset by the captured stones: the partitions of parallel aspiration
+// and the helpers of lazy SMP reach the root score of a single full-window search, and an equally good move
+func TestChildWindows(t *testing.T) {
+	defer func(deterministic bool) { Deterministic = deterministic }(Deterministic)
+
+	for depth := 3; depth <= 7; depth++ {
+		Deterministic = true
+		want := fixedDepth(ASPIRATION, false, 1, depth)
+		Deterministic = false
+		for _, driver := range []string{ASPIRATION, LAZYSMP} {
+			sameResults(t, driver+" on 4 goroutines, depth "+strconv.Itoa(depth), depth, fixedDepth(driver, false, 4, depth), want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package minimax
+
+// opening book consulted before searching, v. package book
+//
+// A position is played from the book if:
+//   - the book has a best move for it and scores for all its successors
+//   - the book is at least as deep as the depth limit, if any
+//
+// The transposition table then holds the book scores and the continuation follows the book moves;
+// no search takes place.
+
+import (
+	"fmt"
+	"sankofa/book"
+	"sankofa/mech"
+	"sankofa/ow"
+)
+
+// consulted by Explore(); nil: none
+var Book *book.Book
+
+// book entry of the root; nil if the result was searched
+func (tt *TT) BookEntry() *book.Entry {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	r := tt.book
+	return r
+}
+
+// fill the transposition table from the book; false if the position is not played from the book
+func (tt *TT) fromBook(game *mech.Game, limits Limits) bool {
+	if Book == nil || Complete || game.GameOver() {
+		return false
+	}
+
+	rank := game.Current().Rank()
+	entry, ok := Book.Lookup(rank)
+	if !ok || entry.Move == book.NO_MOVE {
+		return false
+	}
+	if limits.Depth > 0 && int(entry.Depth) < limits.Depth {
+		ow.Log("book: too shallow:", entry, "depth limit:", limits.Depth)
+		return false
+	}
+
+	// all successors must be scored
+	legalMoves := tt.LegalMoves(rank)
+	if _, ok := legalMoves.Next[entry.Move]; !ok {
+		ow.Log("book: illegal move:", entry)
+		return false
+	}
+	entries := []*book.Entry{entry}
+	for _, move := range legalMoves.Moves {
+		successor, ok := Book.Lookup(legalMoves.Next[move])
+		if !ok {
+			ow.Log("book: successor missing:", mech.MoveToString(move), entry)
+			return false
+		}
+		entries = append(entries, successor)
+	}
+
+	// continuation: book moves as far as they go
+	continuation := game
+	for e := entry; e.Move != book.NO_MOVE && !continuation.GameOver(); {
+		if _, ok := tt.LegalMoves(continuation.Current().Rank()).Next[e.Move]; !ok {
+			ow.Log("book: illegal move:", e)
+			break
+		}
+		continuation = continuation.Move(e.Move)
+		if e, ok = Book.Lookup(continuation.Current().Rank()); !ok {
+			break
+		}
+	}
+	continuation.Cursor = game.Cursor
+
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	for _, e := range entries {
+		tt.tt.Put(e.Rank, int(e.Depth), NewInterval(e.Rank, e.Low, e.High, e.Verdict))
+	}
+	tt._setGame(continuation)
+	tt.depth = int(entry.Depth)
+	tt.base = 0
+	tt.book = entry
+
+	fmt.Println("book:", entry, "⇢", continuation)
+	return true
+}
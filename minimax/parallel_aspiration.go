@@ -1,6 +1,7 @@
 package minimax
 
 import (
+	"context"
 	"fmt"
 	"sankofa/mech"
 	"sankofa/ow"
@@ -17,18 +18,21 @@ var Beta = ow.MAXINT8
 func (tt *TT) Finished() bool {
 	// tt.Game().Current() must have a known score
 	rank := tt.game.Current().Rank()
-	_, ok := tt.tt[rank]
-	if !ok || !tt.tt[rank].Scored() {
+	interval, ok := tt.tt.Get(rank)
+	if !ok || !interval.Scored() {
 		return false
 	}
 
-	// tt.Game().Current().LegalMoves() must have known scores
+	// tt.Game().Current().LegalMoves() must have known scores;
+	// in the order of the sorted moves, not of the map, for stable logs
 	r := true // search for refutation
-	for k, v := range tt.game.Current().LegalMoves().Next {
+	legalMoves := tt.game.Current().LegalMoves()
+	for _, k := range legalMoves.Moves {
+		v := legalMoves.Next[k]
 		// the rank is finished: it has a final score, not an interval
 		// cannot use tt.Known() since this method locks
-		_, ok := tt.tt[v]
-		if !ok || !tt.tt[v].Scored() {
+		interval, ok := tt.tt.Get(v)
+		if !ok || !interval.Scored() {
 			ow.Log("rank:", tt.game.Current(), "move:", mech.MoveToString(k), "next:", v, "not finished")
 			r = false
 			break
@@ -41,11 +45,11 @@ func (tt *TT) Finished() bool {
 // goroutine that wraps a negamax call
 //
 // DESIGN we implement batch processing with channels and method call APIs with mutexes
-func (tt *TT) Worker(α, β int8, depth int) {
+func (tt *TT) Worker(ctx context.Context, α, β int8, depth int) {
 	timeStamp := time.Now().UTC().UnixNano()
 	ow.Log("α:", α, ", β:", β, ", depth:", depth)
 
-	score, verdict, game := tt.NegaMax(tt.Game(), α, β, depth)
+	score, verdict, game := tt.NegaMax(ctx, tt.Game(), α, β, depth)
 	game.Cursor = tt.Game().Cursor
 
 	duration := (float64(time.Now().UTC().UnixNano()) - float64(timeStamp)) / ow.GIGA64F
@@ -81,13 +85,13 @@ func (tt *TT) Worker(α, β int8, depth int) {
 }
 
 // Baudet's parallel aspiration search, a divide and conquer algorithm
-func (tt *TT) Aspiration(intervals Intervals, depth int) *TT {
+func (tt *TT) Aspiration(ctx context.Context, intervals Intervals, depth int) *TT {
 	ow.Log(tt.Game(), intervals, "depth:", depth)
 
 	// start one worker per interval
 	for _, interval := range intervals {
 		tt.Inc()
-		go tt.Worker(interval[0], interval[1], depth)
+		go tt.Worker(ctx, interval[0], interval[1], depth)
 	}
 
 	// finish goroutines
@@ -0,0 +1,316 @@
+package minimax
+
+// proof-number search: can a given side (the attacker) reach 25 stones, i.e., win?
+//
+// α—β with a heuristic rarely proves a verdict in the middle game; proof-number search aims at the verdict only.
+// The attacker is South or North; proving that one side wins is proving that the other side cannot avoid losing.
+//
+// Terminal knowledge:
+//   - captures: more than 24 stones for the attacker proves, 24 or more for the defender disproves,
+//     as does too few stones left on the board for the attacker
+//   - game over: starvation and cycles; each player takes the stones on her side
+//   - database: the exact score of the stones on the board, as in NegaMax; with the Awari rules for cycles
+//
+// Design:
+//   - a tree, not a DAG: cycles depend on the path, so transpositions are not merged
+//   - unknown leaves start with proof and disproof numbers of 1
+//   - the search stops when the root is proven or disproven, or at the duration or node limit (ProofNodes by default)
+//
+// The proof tree holds one proving move at the attacker's nodes and all moves at the defender's;
+// the disproof tree the other way round.
+
+import (
+	"context"
+	"sankofa/db"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"time"
+)
+
+// default node limit of the proof-number search
+var ProofNodes = 1000000
+
+// stones to win
+const WINNING = mech.MAXSTONES/2 + 1
+
+// infinite proof or disproof number
+const PN_INFINITY = ow.MAXINT / 2
+
+// status of a proof
+const (
+	UNPROVEN int8 = iota
+	PROVEN
+	DISPROVEN
+)
+
+// a node of the proof-number search tree
+type ProofNode struct {
+	Move     int8           // move leading here, from the perspective of the side to move before; NO_MOVE at the root
+	PN, DN   int            // proof and disproof numbers
+	Reason   string         // why a terminal node is proven or disproven; empty otherwise
+	Children []*ProofNode   // expanded successors; nil for leaves
+	parent   *ProofNode     // nil at the root
+	position *mech.Position // from the perspective of the side to move
+	attacker bool           // the attacker is to move
+}
+
+// outcome of a proof-number search
+type Proof struct {
+	South   bool       // the attacker is South
+	Root    *ProofNode // search tree
+	Game    *mech.Game // game at the root
+	Nodes   int        // tree nodes
+	Elapsed float64    // seconds
+}
+
+// status of a node
+func (node *ProofNode) Status() int8 {
+	switch {
+	case node.PN == 0:
+		return PROVEN
+	case node.DN == 0:
+		return DISPROVEN
+	default:
+		return UNPROVEN
+	}
+}
+
+func StatusToString(status int8) string {
+	switch status {
+	case PROVEN:
+		return "proven"
+	case DISPROVEN:
+		return "disproven"
+	default:
+		return "unproven"
+	}
+}
+
+func (proof *Proof) String() string {
+	side := "North"
+	if proof.South {
+		side = "South"
+	}
+	return side + " reaches " + ow.Thousands(WINNING) + ": " + StatusToString(proof.Root.Status()) +
+		" | pn: " + PNString(proof.Root.PN) + ", dn: " + PNString(proof.Root.DN) +
+		" | nodes: " + ow.Thousands(proof.Nodes) +
+		" | " + strconv.FormatFloat(proof.Elapsed, 'f', 2, 64) + " sec."
+}
+
+// proof or disproof number; ∞ if settled
+func PNString(n int) string {
+	if n >= PN_INFINITY {
+		return "∞"
+	}
+	return ow.Thousands(n)
+}
+
+// game leading to the node, continuing the root game
+func (proof *Proof) GameOf(node *ProofNode) *mech.Game {
+	var moves []int8
+	for n := node; n.parent != nil; n = n.parent {
+		moves = append(moves, n.Move)
+	}
+
+	game := proof.Game
+	for i := len(moves) - 1; i >= 0; i-- {
+		game = game.Move(moves[i])
+	}
+	return game
+}
+
+// children in the proof or disproof tree; all children if unproven
+func (node *ProofNode) Proof() []*ProofNode {
+	status := node.Status()
+	if status == UNPROVEN {
+		return node.Children
+	}
+
+	// one child suffices at the attacker's proven and at the defender's disproven nodes
+	one := (status == PROVEN) == node.attacker
+	r := make([]*ProofNode, 0, len(node.Children))
+	for _, child := range node.Children {
+		if child.Status() != status {
+			continue
+		}
+		r = append(r, child)
+		if one {
+			break
+		}
+	}
+	return r
+}
+
+////////////////////////////////////////////////////////////////
+// SEARCH
+////////////////////////////////////////////////////////////////
+
+// try to prove that South (or North) reaches 25 stones from the current position of the game;
+// the duration and node limits apply; stops early when the context is cancelled
+func Prove(ctx context.Context, game *mech.Game, south bool, limits Limits) *Proof {
+	begin := time.Now().UTC().UnixNano()
+	ctx, cancel := withDuration(ctx, limits.Duration)
+	defer cancel()
+
+	nodes := limits.Nodes
+	if nodes <= 0 {
+		nodes = ProofNodes
+	}
+
+	// truncate the continuation
+	root := game.Clone()
+	root.Positions = root.Positions[:root.Cursor+1]
+	root.Moves = root.Moves[:root.Cursor]
+
+	proof := &Proof{South: south, Game: root, Nodes: 1}
+	proof.Root = &ProofNode{Move: NO_MOVE, position: root.Current(), attacker: south == ow.Even(root.Cursor)}
+	proof.evaluate(proof.Root)
+
+	for proof.Root.Status() == UNPROVEN && proof.Nodes < nodes && ctx.Err() == nil {
+		node := proof.mostProving()
+		proof.expand(node)
+		proof.backup(node)
+	}
+
+	proof.Elapsed = float64(time.Now().UTC().UnixNano()-begin) / ow.GIGA64F
+	ow.Log("proof:", proof)
+	return proof
+}
+
+// descend to the leaf that reduces the proof or disproof number of the root most
+func (proof *Proof) mostProving() *ProofNode {
+	node := proof.Root
+	for node.Children != nil {
+		best := node.Children[0]
+		for _, child := range node.Children[1:] {
+			if (node.attacker && child.PN < best.PN) || (!node.attacker && child.DN < best.DN) {
+				best = child
+			}
+		}
+		node = best
+	}
+	return node
+}
+
+// generate and evaluate the successors of a leaf
+func (proof *Proof) expand(node *ProofNode) {
+	legalMoves := node.position.LegalMoves()
+	node.Children = make([]*ProofNode, 0, len(legalMoves.Moves))
+	for _, move := range legalMoves.Moves {
+		child := &ProofNode{
+			Move:     move,
+			parent:   node,
+			position: node.position.Move(move),
+			attacker: !node.attacker,
+		}
+		proof.evaluate(child)
+		node.Children = append(node.Children, child)
+	}
+	proof.Nodes += len(node.Children)
+}
+
+// update the proof and disproof numbers from a node up to the root
+func (proof *Proof) backup(node *ProofNode) {
+	for ; node != nil; node = node.parent {
+		pn, dn := node.PN, node.DN
+		if node.attacker {
+			// OR node: one proven child suffices
+			node.PN, node.DN = PN_INFINITY, 0
+			for _, child := range node.Children {
+				node.PN = ow.Min(node.PN, child.PN)
+				node.DN = ow.Min(PN_INFINITY, node.DN+child.DN)
+			}
+		} else {
+			// AND node: all children must be proven
+			node.PN, node.DN = 0, PN_INFINITY
+			for _, child := range node.Children {
+				node.PN = ow.Min(PN_INFINITY, node.PN+child.PN)
+				node.DN = ow.Min(node.DN, child.DN)
+			}
+		}
+		if node.PN == pn && node.DN == dn && node.parent != nil {
+			// ancestors unchanged
+			break
+		}
+	}
+}
+
+// initial proof and disproof numbers; terminal knowledge
+func (proof *Proof) evaluate(node *ProofNode) {
+	node.PN, node.DN = 1, 1
+
+	// stones taken by the attacker and by the defender so far
+	position := node.position
+	attacker, defender := position.Scores[0], position.Scores[1]
+	if !node.attacker {
+		attacker, defender = defender, attacker
+	}
+
+	// each player takes the stones on her side
+	split := func() (int8, int8) {
+		if node.attacker {
+			return attacker + position.SouthStones(), defender + position.NorthStones()
+		}
+		return attacker + position.NorthStones(), defender + position.SouthStones()
+	}
+
+	switch {
+	case attacker >= WINNING:
+		proof.settle(node, true, "captures")
+	case defender >= mech.MAXSTONES/2:
+		proof.settle(node, false, "captures")
+	case attacker+position.Stones() < WINNING:
+		proof.settle(node, false, "out of reach")
+	case position.Starved():
+		a, _ := split()
+		proof.settle(node, a >= WINNING, "starved")
+	case proof.cycle(node):
+		a, _ := split()
+		proof.settle(node, a >= WINNING, "cycle")
+	case !db.Covers(position.Rank()):
+		// not in the database
+		return
+	default:
+		// exact score of the stones on the board, for the side to move
+		score, ok := db.GetScore(position.Rank())
+		if !ok {
+			return
+		}
+		share := (position.Stones() + score) / 2
+		if !node.attacker {
+			share = position.Stones() - share
+		}
+		proof.settle(node, attacker+share >= WINNING, "database")
+	}
+}
+
+// proven or disproven terminal node
+func (proof *Proof) settle(node *ProofNode, proven bool, reason string) {
+	node.Reason = reason
+	if proven {
+		node.PN, node.DN = 0, PN_INFINITY
+	} else {
+		node.PN, node.DN = PN_INFINITY, 0
+	}
+}
+
+// does the node repeat a board of its path or of the root game?
+func (proof *Proof) cycle(node *ProofNode) bool {
+	if node.parent == nil {
+		return proof.Game.Cycle()
+	}
+
+	// any earlier board, as Game.Cycle() does
+	for n := node.parent; n != nil; n = n.parent {
+		if n.position.EQ(node.position) {
+			return true
+		}
+	}
+	for i := 0; i < len(proof.Game.Positions); i++ {
+		if proof.Game.Positions[i].EQ(node.position) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package minimax
+
+import (
+	"context"
+	"path/filepath"
+	"sankofa/db"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"testing"
+)
+
+// a database scoring only the position after the initial move D: North, to move, loses all stones
+func loserDatabase(t *testing.T) {
+	t.Helper()
+	defer func(fileName string) { db.FileName = fileName }(db.FileName)
+	db.FileName = filepath.Join(t.TempDir(), "oware.db")
+	db.Open()
+	t.Cleanup(db.Close)
+
+	db.SetScore(mech.StringToGame(INITIAL).Current().Move(mech.D).Rank(), -mech.MAXSTONES)
+}
+
+// South wins by D, a database position; North cannot win
+func TestProve(t *testing.T) {
+	loserDatabase(t)
+	game := mech.StringToGame(INITIAL)
+
+	proof := Prove(context.Background(), game, true, Limits{Nodes: 1000})
+	if proof.Root.Status() != PROVEN {
+		t.Fatalf("South: %s", StatusToString(proof.Root.Status()))
+	}
+	children := proof.Root.Proof()
+	if len(children) != 1 || children[0].Move != mech.D || children[0].Reason != "database" {
+		t.Errorf("South: proof: %v", children)
+	}
+	if proof.Nodes != 1+len(game.Current().LegalMoves().Moves) {
+		t.Errorf("South: %d nodes; want the root and its children", proof.Nodes)
+	}
+
+	proof = Prove(context.Background(), game, false, Limits{Nodes: 1000})
+	if proof.Root.Status() != DISPROVEN {
+		t.Fatalf("North: %s", StatusToString(proof.Root.Status()))
+	}
+	children = proof.Root.Proof()
+	if len(children) != 1 || children[0].Move != mech.D || children[0].Reason != "database" {
+		t.Errorf("North: disproof: %v", children)
+	}
+}
+
+// without knowledge, the search stops at the node limit
+func TestProveNodes(t *testing.T) {
+	game := mech.StringToGame(INITIAL)
+	proof := Prove(context.Background(), game, true, Limits{Nodes: 100})
+	if proof.Root.Status() != UNPROVEN || proof.Nodes < 100 || proof.Nodes >= 100+mech.MOVE_CAP {
+		t.Errorf("%s, %d nodes; want unproven, 100 nodes and at most one expansion more", StatusToString(proof.Root.Status()), proof.Nodes)
+	}
+}
+
+// positions of 47 stones, not covered by the database, are searched rather than looked up
+func TestProveLevel47(t *testing.T) {
+	loserDatabase(t)
+	rank := ow.LevelUpperLimits[46] + 1
+	for mech.StringToGame("/" + strconv.FormatInt(rank, 10)).GameOver() {
+		rank++
+	}
+	game := mech.StringToGame("/" + strconv.FormatInt(rank, 10))
+
+	proof := Prove(context.Background(), game, true, Limits{Nodes: 100})
+	if proof.Nodes < 100 {
+		t.Errorf("%v: %d nodes", game, proof.Nodes)
+	}
+}
@@ -0,0 +1,33 @@
+package minimax
+
+// quiescence search: the bottom of the search is extended while the position is noisy, i.e., while
+//   - a move captures stones, or
+//   - the opponent is starved and must be fed.
+//
+// Quiescence nodes below the nominal depth visit only the captures, unless feeding is forced.
+// The side to move may also stand pat on the evaluation, except when feeding is forced.
+// The database still ends the search where it has a score.
+// A cancelled search stops at once, without extending; quiescence results are not stored in the TT.
+
+import (
+	"sankofa/mech"
+)
+
+// maximum number of plies below the nominal depth, e.g., 8; 0 disables quiescence
+var Quiescence = 8
+
+// is the position at or below the bottom worth extending?
+func (tt *TT) noisy(game *mech.Game, legalMoves *mech.LegalMoves, depth int) bool {
+	if depth <= -Quiescence {
+		return false
+	}
+	if game.Current().Reverse().Starved() {
+		return true
+	}
+	for _, score := range legalMoves.Score {
+		if score != 0 {
+			return true
+		}
+	}
+	return false
+}
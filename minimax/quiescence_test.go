@@ -0,0 +1,49 @@
+package minimax
+
+import (
+	"context"
+	"sankofa/mech"
+	"sankofa/ow"
+	"testing"
+)
+
+// score of a bottom node: the nominal depth is reached at once
+func bottom(game *mech.Game, quiescence int) int8 {
+	defer func(q int) { Quiescence = q }(Quiescence)
+	Quiescence = quiescence
+
+	level := ow.Level(game.Current().Rank())
+	score, _, _ := NewTT(game).NegaMax(context.Background(), game, -level, level, 0)
+	return score
+}
+
+// the first position of the opening along the first legal moves with a capture to make
+func noisy() *mech.Game {
+	game := mech.StringToGame(INITIAL)
+	for !game.GameOver() {
+		for _, score := range game.Current().LegalMoves().Score {
+			if score != 0 {
+				return game
+			}
+		}
+		game = game.Move(game.Current().LegalMoves().Moves[0])
+	}
+	return nil
+}
+
+// quiescence changes the score of a noisy bottom node only: a quiet one keeps its evaluation
+func TestQuiescence(t *testing.T) {
+	quiet := mech.StringToGame(INITIAL)
+	if got, want := bottom(quiet, 8), bottom(quiet, 0); got != want {
+		t.Errorf("%v: quiet: %d; want the evaluation %d", quiet, got, want)
+	}
+
+	game := noisy()
+	if game == nil {
+		t.Fatal("no capture")
+	}
+	// standing pat, the side to move gets at least the evaluation
+	if got, evaluation := bottom(game, 8), bottom(game, 0); got <= evaluation {
+		t.Errorf("%v: noisy: %d; want more than the evaluation %d", game, got, evaluation)
+	}
+}
@@ -0,0 +1,139 @@
+package minimax
+
+// search results and progress events, for front-ends other than the web server
+//
+// SearchInfo describes a completed deepener iteration; SearchResult the search as a whole.
+// Both are snapshots: they do not change when the transposition table does.
+//
+// Progress is reported by a callback, v. *TT.SetProgress():
+//   - it is called by the iterative deepener, between iterations, hence it shall return quickly
+//   - ProgressChannel() forwards the events to a channel, dropping those not received in time
+//
+// Moves are houses from the perspective of the side to move, as in Line; scores exclude the captures so far.
+
+import (
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+)
+
+// no move: game over at the root, or no completed iteration
+const NO_MOVE = int8(-1)
+
+// a completed deepener iteration
+type SearchInfo struct {
+	Depth      int        // reached depth, i.e., iteration depth less base
+	Move       int8       // best move; NO_MOVE if none
+	Interval   *Interval  // root score range, for the side to move; nil if none
+	Verdict    int8       // for the side to move
+	PV         *mech.Game // principal variation; cursor at the root
+	Nodes      int        // visited nodes, all iterations
+	Database   int        // bottom-level scores from the database, all iterations
+	Hits       int        // nodes answered by the transposition table, all iterations
+	Quiescence int        // bottom-level nodes extended by the quiescence search, this iteration
+	CutOffs    int        // this iteration
+	FirstMove  int        // cut-offs by the first searched move, this iteration
+	Elapsed    float64    // seconds since the search began
+}
+
+// outcome of the iterative deepener: its last completed iteration
+type SearchResult struct {
+	SearchInfo
+	Evaluator string // bottom-level evaluation
+	Limits    Limits // as requested
+	Book      bool   // played from the opening book, not searched
+}
+
+// callback receiving the deepener's progress
+type Progress func(info *SearchInfo)
+
+func (info *SearchInfo) String() string {
+	move := "none"
+	if info.Move != NO_MOVE {
+		move = mech.MoveToString(info.Move)
+	}
+	return "depth: " + ow.Thousands(info.Depth) +
+		" | move: " + move +
+		" | score: " + info.Interval.String() +
+		" | nodes: " + ow.Thousands(info.Nodes) +
+		", database: " + ow.Thousands(info.Database) +
+		", TT hits: " + ow.Thousands(info.Hits) +
+		", quiescence: " + ow.Thousands(info.Quiescence) +
+		", first-move cut-offs: " + FirstMoveRate(info.CutOffs, info.FirstMove) +
+		" | " + strconv.FormatFloat(info.Elapsed, 'f', 2, 64) + " sec." +
+		" | " + info.PV.String()
+}
+
+func (result *SearchResult) String() string {
+	r := result.SearchInfo.String() +
+		" | evaluator: " + result.Evaluator +
+		" | limits: " + result.Limits.String()
+	if result.Book {
+		r += " | book"
+	}
+	return r
+}
+
+// report the deepener's progress to a callback; before Explore(); nil for none
+func (tt *TT) SetProgress(progress Progress) *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.progress = progress
+	return tt
+}
+
+// forward progress events to a channel; events are dropped while the channel is full
+func ProgressChannel(channel chan<- *SearchInfo) Progress {
+	return func(info *SearchInfo) {
+		select {
+		case channel <- info:
+		default:
+			ow.Log("progress: channel full: dropped:", info)
+		}
+	}
+}
+
+// snapshot of the current state of the search
+func (tt *TT) Info() *SearchInfo {
+	game := tt.Game()
+
+	info := new(SearchInfo)
+	// 0 before the first iteration
+	if depth, base := tt.Depth(), tt.Base(); depth >= base {
+		info.Depth = depth - base
+	}
+	info.Move = NO_MOVE
+	if game.Cursor < len(game.Moves) {
+		info.Move = game.Moves[game.Cursor]
+	}
+	info.Interval = tt.Interval(game.Current().Rank())
+	if info.Interval != nil {
+		info.Verdict = info.Interval.Verdict()
+	}
+	info.PV = game
+	info.Nodes = tt.Nodes()
+	info.Database = tt.Database()
+	info.Hits = tt.Hits()
+	info.Quiescence = tt.Quiescence()
+	info.CutOffs, info.FirstMove = tt.CutOffs()
+	info.Elapsed = tt.Elapsed()
+	return info
+}
+
+// result of Explore()
+func (tt *TT) Result() *SearchResult {
+	return &SearchResult{*tt.Info(), tt.Evaluator().String(), tt.Limits(), tt.BookEntry() != nil}
+}
+
+// call the progress callback, if any
+func (tt *TT) report() *TT {
+	tt.mutex.RLock()
+	progress := tt.progress
+	tt.mutex.RUnlock()
+
+	if progress != nil {
+		progress(tt.Info())
+	}
+	return tt
+}
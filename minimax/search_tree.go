@@ -0,0 +1,238 @@
+package minimax
+
+// recording of the searched tree, for post-processing, v. *TT.SetRecord()
+//
+// Trace prints while searching and Complete disables the cut-offs; neither can be post-processed.
+// The recorded tree keeps, for each negaMax call up to a number of plies below the root:
+//   - the rank, the move leading there, the α—β window and the remaining depth
+//   - the returned score and where it comes from: terminal, TT, cache, database, heuristic, search or quiescence
+//   - why the node stopped early, if it did: a β cut-off, a win, a TT bound or standing pat
+//
+// Each root call is a tree of its own, e.g., an MTD(f) pass or an aspiration re-search.
+// Deeper nodes, and nodes beyond TREE_NODES, are counted but not kept.
+// The tree of the last completed iteration is kept with the result; export as JSON or Graphviz DOT.
+//
+// Recording, like Trace, disables parallelism: the nodes are those of the single-threaded search, in search order.
+
+import (
+	"encoding/json"
+	"sankofa/mech"
+	"sankofa/ow"
+	"strconv"
+	"strings"
+)
+
+// recorded nodes at most
+const TREE_NODES = 100000
+
+// where the score of a node comes from
+const (
+	SOURCE_TERMINAL   = "terminal"   // game over: final score, starved or cycle
+	SOURCE_TT         = "TT"         // transposition table of the search
+	SOURCE_CACHE      = "cache"      // earlier search, v. Cache
+	SOURCE_DATABASE   = "database"   // bottom: exact score
+	SOURCE_HEURISTIC  = "heuristic"  // bottom: evaluator
+	SOURCE_SEARCH     = "search"     // the moves below
+	SOURCE_QUIESCENCE = "quiescence" // the captures and forced feeding below the bottom
+	SOURCE_CANCELLED  = "cancelled"  // evaluator, the search being stopped
+)
+
+// why a node stopped before searching all moves
+const (
+	CUT_BETA     = "β"         // score at least β
+	CUT_WIN      = "win"       // proven win
+	CUT_BOUND    = "bound"     // TT bound outside the window
+	CUT_STANDPAT = "stand pat" // quiescence: the evaluation is at least β
+)
+
+// a recorded negaMax call; scores from the perspective of the side to move
+type TreeNode struct {
+	Rank     int64       `json:"rank"`
+	Move     string      `json:"move,omitempty"` // move leading here, as in the trail; empty at the root
+	Trail    string      `json:"trail"`          // game leading here
+	Alpha    int8        `json:"alpha"`
+	Beta     int8        `json:"beta"`
+	Depth    int         `json:"depth"` // remaining; negative in the quiescence search
+	Score    int8        `json:"score"`
+	Source   string      `json:"source"`
+	Cut      string      `json:"cut,omitempty"`
+	Children []*TreeNode `json:"children,omitempty"`
+	ply      int         // below the root
+}
+
+// the recorded calls of a deepener iteration
+type SearchTree struct {
+	Game     string      `json:"game"`     // root
+	Plies    int         `json:"plies"`    // recorded below the root
+	Depth    int         `json:"depth"`    // of the iteration
+	Roots    []*TreeNode `json:"roots"`    // root calls, in search order
+	Recorded int         `json:"recorded"` // nodes kept
+	Skipped  int         `json:"skipped"`  // nodes too deep or beyond TREE_NODES
+}
+
+// single-threaded recording of an iteration
+type recorder struct {
+	tree  *SearchTree
+	stack []*TreeNode // path to the node being searched
+	root  int         // cursor of the root
+}
+
+func newRecorder(plies int) *recorder {
+	return &recorder{tree: &SearchTree{Plies: plies}}
+}
+
+// record the searched tree up to the given plies below the root; 0: off
+func (tt *TT) SetRecord(plies int) *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.recorder = nil
+	if plies > 0 {
+		tt.recorder = newRecorder(plies)
+	}
+	return tt
+}
+
+// is the search recorded?
+func (tt *TT) Recording() bool {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	return tt.recorder != nil
+}
+
+// recorded tree of the iteration, a copy with its depth; nil if not recorded
+func (tt *TT) Tree() *SearchTree {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	if tt.recorder == nil {
+		return nil
+	}
+	tree := *tt.recorder.tree
+	tree.Depth = tt.depth
+	return &tree
+}
+
+// a node is entered; nil if not recorded
+func (tt *TT) enter(game *mech.Game, α, β int8, depth int) *TreeNode {
+	r := tt.recorder
+	if r == nil {
+		return nil
+	}
+
+	// a root call, or a node below the path being searched
+	if len(r.stack) == 0 {
+		r.root = game.Cursor
+	}
+	ply := game.Cursor - r.root
+	if ply > r.tree.Plies || r.tree.Recorded >= TREE_NODES || len(r.stack) > 0 && r.stack[len(r.stack)-1].ply != ply-1 {
+		r.tree.Skipped++
+		return nil
+	}
+	r.tree.Recorded++
+
+	node := &TreeNode{Rank: game.Current().Rank(), Trail: game.String(), Alpha: α, Beta: β, Depth: depth, Source: SOURCE_SEARCH, ply: ply}
+	if ply > 0 {
+		node.Move = moveLabel(game)
+		parent := r.stack[len(r.stack)-1]
+		parent.Children = append(parent.Children, node)
+	} else {
+		if r.tree.Game == "" {
+			r.tree.Game = node.Trail
+		}
+		r.tree.Roots = append(r.tree.Roots, node)
+	}
+	r.stack = append(r.stack, node)
+	return node
+}
+
+// a recorded node returns
+func (tt *TT) leave(node *TreeNode, score *int8, source, cut *string) {
+	if node == nil {
+		return
+	}
+	node.Score, node.Source, node.Cut = *score, *source, *cut
+
+	r := tt.recorder
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+// the last move of the game: upper case for South, lower case for North
+func moveLabel(game *mech.Game) string {
+	move := strings.ToLower(mech.MoveToString(game.Moves[game.Cursor-1]))
+	if ow.Odd(game.Cursor) {
+		move = strings.ToUpper(move)
+	}
+	return move
+}
+
+////////////////////////////////////////////////////////////////
+// EXPORT
+////////////////////////////////////////////////////////////////
+
+func (tree *SearchTree) String() string {
+	return tree.Game + " | depth: " + ow.Thousands(tree.Depth) +
+		" | plies: " + ow.Thousands(tree.Plies) +
+		" | root calls: " + ow.Thousands(len(tree.Roots)) +
+		" | recorded: " + ow.Thousands(tree.Recorded) +
+		", skipped: " + ow.Thousands(tree.Skipped)
+}
+
+func (node *TreeNode) String() string {
+	label := node.Move
+	if label == "" {
+		label = "root"
+	}
+	r := label + ": " + ow.Thousands(node.Score) + " ∈? [" + ow.Thousands(node.Alpha) + ", " + ow.Thousands(node.Beta) + "] " + node.Source
+	if node.Cut != "" {
+		r += ", cut: " + node.Cut
+	}
+	return r
+}
+
+// indented JSON
+func (tree *SearchTree) JSON() []byte {
+	r, err := json.MarshalIndent(tree, "", " ")
+	ow.Check(err)
+	return r
+}
+
+// Graphviz digraph, e.g., dot -Tsvg; cut-offs in red, terminal nodes as boxes
+func (tree *SearchTree) DOT() string {
+	var r strings.Builder
+	r.WriteString("digraph search {\n")
+	r.WriteString("\tlabel=" + strconv.Quote(tree.String()) + ";\n")
+	r.WriteString("\tnode [fontname=\"Courier\", fontsize=10];\n")
+	id := 0
+	for _, root := range tree.Roots {
+		root.dot(&r, &id)
+	}
+	r.WriteString("}\n")
+	return r.String()
+}
+
+// add the node and its subtree; returns the node's id
+func (node *TreeNode) dot(r *strings.Builder, id *int) int {
+	self := *id
+	*id++
+
+	label := node.Move
+	if label == "" {
+		label = "root"
+	}
+	label += " " + ow.Thousands(node.Score) + "\n[" + ow.Thousands(node.Alpha) + ", " + ow.Thousands(node.Beta) + "] d" + ow.Thousands(node.Depth) + "\n" + node.Source
+	attributes := "label=" + strconv.Quote(label)
+	if node.Source == SOURCE_TERMINAL {
+		attributes += ", shape=box"
+	}
+	if node.Cut != "" {
+		attributes += ", color=red, xlabel=" + strconv.Quote(node.Cut)
+	}
+	r.WriteString("\tn" + strconv.Itoa(self) + " [" + attributes + "];\n")
+
+	for _, child := range node.Children {
+		r.WriteString("\tn" + strconv.Itoa(self) + " -> n" + strconv.Itoa(child.dot(r, id)) + ";\n")
+	}
+	return self
+}
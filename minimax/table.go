@@ -0,0 +1,228 @@
+package minimax
+
+// fixed-capacity hash table indexed by position rank; bounded memory for long analyses.
+//
+// Each bucket holds two slots:
+//   - depth-preferred: keeps the entry searched to the largest remaining depth
+//   - always-replace: takes whatever the depth-preferred slot rejects or evicts
+//
+// Buckets are guarded by a fixed number of striped locks rather than one global mutex.
+
+import (
+	"sankofa/ow"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// megabytes for the transposition tables, the memoization caches and the analysis results of a search
+var Hash = 128
+
+// number of lock stripes; a power of 2
+const STRIPES = 1024
+
+// an entry; key is rank+1, 0 means empty
+type slot[V any] struct {
+	key   int64
+	depth int
+	value V
+}
+
+type bucket[V any] struct {
+	deep, recent slot[V]
+}
+
+// thread-safe, fixed-size table
+type Table[V any] struct {
+	buckets []bucket[V]
+	mask    uint64
+	locks   [STRIPES]sync.Mutex
+
+	// statistics
+	size       atomic.Int64 // occupied slots
+	hits       atomic.Int64 // successful look-ups
+	misses     atomic.Int64 // look-ups of absent ranks
+	collisions atomic.Int64 // misses on a bucket occupied by other ranks
+	overwrites atomic.Int64 // entries evicted by other ranks
+}
+
+// table that fits into the given number of bytes;
+// extra is the estimated heap size referenced by each value
+func NewTable[V any](bytes, extra int) *Table[V] {
+	size := int(unsafe.Sizeof(bucket[V]{})) + 2*extra
+	count := ow.Max(bytes/size, 1)
+
+	// round down to a power of 2
+	buckets := 1
+	for buckets*2 <= count {
+		buckets *= 2
+	}
+
+	table := new(Table[V])
+	table.buckets = make([]bucket[V], buckets)
+	table.mask = uint64(buckets - 1)
+	ow.Log("buckets:", buckets, "bytes:", buckets*size)
+	return table
+}
+
+// Fibonacci hashing
+func (table *Table[V]) index(rank int64) uint64 {
+	return (uint64(rank) * 0x9E3779B97F4A7C15 >> 17) & table.mask
+}
+
+// lock the stripe of a bucket
+func (table *Table[V]) lock(i uint64) func() {
+	mutex := &table.locks[i&(STRIPES-1)]
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// no-lock: slot holding the rank, or nil
+func (b *bucket[V]) find(key int64) *slot[V] {
+	switch key {
+	case b.deep.key:
+		return &b.deep
+	case b.recent.key:
+		return &b.recent
+	default:
+		return nil
+	}
+}
+
+// no-lock: store an entry; depth-preferred, then always-replace
+func (table *Table[V]) put(b *bucket[V], key int64, depth int, value V) {
+	// update in place
+	if s := b.find(key); s != nil {
+		if s == &b.recent && depth >= b.deep.depth {
+			b.deep, b.recent = slot[V]{key, depth, value}, b.deep
+			return
+		}
+		s.depth = depth
+		s.value = value
+		return
+	}
+
+	if b.recent.key != 0 {
+		table.overwrites.Add(1)
+	} else {
+		table.size.Add(1)
+	}
+	if b.deep.key == 0 || depth >= b.deep.depth {
+		// the evicted depth-preferred entry is still recent
+		b.deep, b.recent = slot[V]{key, depth, value}, b.deep
+	} else {
+		b.recent = slot[V]{key, depth, value}
+	}
+}
+
+// look up a rank
+func (table *Table[V]) Get(rank int64) (V, bool) {
+	i := table.index(rank)
+	defer table.lock(i)()
+
+	b := &table.buckets[i]
+	if s := b.find(rank + 1); s != nil {
+		table.hits.Add(1)
+		return s.value, true
+	}
+
+	table.misses.Add(1)
+	if b.deep.key != 0 {
+		table.collisions.Add(1)
+	}
+	var zero V
+	return zero, false
+}
+
+// look up a rank and the remaining depth it was stored with
+func (table *Table[V]) Probe(rank int64) (V, int, bool) {
+	i := table.index(rank)
+	defer table.lock(i)()
+
+	b := &table.buckets[i]
+	if s := b.find(rank + 1); s != nil {
+		table.hits.Add(1)
+		return s.value, s.depth, true
+	}
+
+	table.misses.Add(1)
+	if b.deep.key != 0 {
+		table.collisions.Add(1)
+	}
+	var zero V
+	return zero, 0, false
+}
+
+// store a value unless the rank is already stored with a larger remaining depth
+func (table *Table[V]) Offer(rank int64, depth int, value V) {
+	i := table.index(rank)
+	defer table.lock(i)()
+
+	b := &table.buckets[i]
+	if s := b.find(rank + 1); s != nil && s.depth > depth {
+		return
+	}
+	table.put(b, rank+1, depth, value)
+}
+
+// store a value computed with the given remaining depth
+func (table *Table[V]) Put(rank int64, depth int, value V) {
+	i := table.index(rank)
+	defer table.lock(i)()
+
+	table.put(&table.buckets[i], rank+1, depth, value)
+}
+
+// atomic read-modify-write; update() receives the stored value, if any
+func (table *Table[V]) Update(rank int64, depth int, update func(old V, ok bool) V) V {
+	i := table.index(rank)
+	defer table.lock(i)()
+
+	b := &table.buckets[i]
+	var old V
+	s := b.find(rank + 1)
+	if s != nil {
+		old = s.value
+	}
+	value := update(old, s != nil)
+	table.put(b, rank+1, depth, value)
+	return value
+}
+
+// visit all entries; the table must not be modified meanwhile
+func (table *Table[V]) Range(visit func(rank int64, depth int, value V)) {
+	for i := range table.buckets {
+		b := &table.buckets[i]
+		if b.deep.key != 0 {
+			visit(b.deep.key-1, b.deep.depth, b.deep.value)
+		}
+		if b.recent.key != 0 {
+			visit(b.recent.key-1, b.recent.depth, b.recent.value)
+		}
+	}
+}
+
+// remove all entries and reset the statistics
+func (table *Table[V]) Clear() *Table[V] {
+	clear(table.buckets)
+	table.size.Store(0)
+	table.hits.Store(0)
+	table.misses.Store(0)
+	table.collisions.Store(0)
+	table.overwrites.Store(0)
+	return table
+}
+
+// number of stored entries
+func (table *Table[V]) Len() int {
+	return int(table.size.Load())
+}
+
+func (table *Table[V]) String() string {
+	return "size: " + ow.Thousands(table.size.Load()) +
+		"/" + ow.Thousands(int64(2*len(table.buckets))) +
+		", hits: " + ow.Thousands(table.hits.Load()) +
+		", misses: " + ow.Thousands(table.misses.Load()) +
+		", collisions: " + ow.Thousands(table.collisions.Load()) +
+		", overwrites: " + ow.Thousands(table.overwrites.Load())
+}
@@ -0,0 +1,148 @@
+package minimax
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestTableGetPut(t *testing.T) {
+	table := NewTable[int](1<<10, 0)
+	table.Put(5, 3, 50)
+
+	if value, ok := table.Get(5); !ok || value != 50 {
+		t.Errorf("Get(5) = %v, %v; want 50, true", value, ok)
+	}
+	if value, depth, ok := table.Probe(5); !ok || value != 50 || depth != 3 {
+		t.Errorf("Probe(5) = %v, %v, %v; want 50, 3, true", value, depth, ok)
+	}
+	if _, ok := table.Get(6); ok {
+		t.Error("Get(6): found, never stored")
+	}
+	// rank 0 is a valid key: keys are stored as rank+1
+	table.Put(0, 1, 7)
+	if value, ok := table.Get(0); !ok || value != 7 {
+		t.Errorf("Get(0) = %v, %v; want 7, true", value, ok)
+	}
+}
+
+// a single bucket: every rank collides
+func TestTableReplacement(t *testing.T) {
+	table := NewTable[string](1, 0)
+	table.Put(1, 5, "a")
+	table.Put(2, 2, "b")
+	// shallower than the depth-preferred slot: replaces the recent one
+	table.Put(3, 1, "c")
+	if _, ok := table.Get(2); ok {
+		t.Error("rank 2 not evicted by the always-replace slot")
+	}
+	for rank, want := range map[int64]string{1: "a", 3: "c"} {
+		if value, ok := table.Get(rank); !ok || value != want {
+			t.Errorf("Get(%d) = %v, %v; want %v, true", rank, value, ok, want)
+		}
+	}
+
+	// deeper: takes the depth-preferred slot, whose entry becomes the recent one
+	table.Put(4, 9, "d")
+	if _, ok := table.Get(3); ok {
+		t.Error("rank 3 not evicted")
+	}
+	for rank, want := range map[int64]string{1: "a", 4: "d"} {
+		if value, ok := table.Get(rank); !ok || value != want {
+			t.Errorf("Get(%d) = %v, %v; want %v, true", rank, value, ok, want)
+		}
+	}
+	if table.Len() != 2 {
+		t.Errorf("Len() = %d; want 2", table.Len())
+	}
+}
+
+func TestTableOffer(t *testing.T) {
+	table := NewTable[int](1<<10, 0)
+	table.Put(1, 5, 10)
+	table.Offer(1, 3, 20)
+	if value, _ := table.Get(1); value != 10 {
+		t.Errorf("shallower offer accepted: %d", value)
+	}
+	table.Offer(1, 5, 30)
+	if value, _ := table.Get(1); value != 30 {
+		t.Errorf("offer as deep refused: %d", value)
+	}
+	table.Offer(1, 6, 40)
+	if value, depth, _ := table.Probe(1); value != 40 || depth != 6 {
+		t.Errorf("deeper offer: %d @%d; want 40 @6", value, depth)
+	}
+}
+
+func TestTableUpdate(t *testing.T) {
+	table := NewTable[int](1<<10, 0)
+	add := func(old int, ok bool) int {
+		if !ok {
+			return 1
+		}
+		return old + 1
+	}
+	for i := 1; i <= 3; i++ {
+		if value := table.Update(9, 0, add); value != i {
+			t.Errorf("Update #%d = %d", i, value)
+		}
+	}
+}
+
+func TestTableRangeClear(t *testing.T) {
+	table := NewTable[int](1<<16, 0)
+	want := map[int64]int{}
+	for rank := int64(0); rank < 100; rank++ {
+		table.Put(rank, int(rank%7), int(rank)*2)
+		want[rank] = int(rank) * 2
+	}
+
+	got := map[int64]int{}
+	table.Range(func(rank int64, depth int, value int) {
+		if depth != int(rank%7) {
+			t.Errorf("rank %d: depth %d", rank, depth)
+		}
+		got[rank] = value
+	})
+	for rank, value := range got {
+		if want[rank] != value {
+			t.Errorf("rank %d: %d; want %d", rank, value, want[rank])
+		}
+	}
+	if len(got) != table.Len() {
+		t.Errorf("Range visited %d entries, Len() = %d", len(got), table.Len())
+	}
+
+	table.Clear()
+	if table.Len() != 0 {
+		t.Errorf("Len() = %d after Clear()", table.Len())
+	}
+	table.Range(func(rank int64, depth int, value int) {
+		t.Errorf("rank %d left after Clear()", rank)
+	})
+}
+
+// lock stripes: concurrent updates are not lost; run with -race
+func TestTableConcurrent(t *testing.T) {
+	const goroutines, ranks = 8, 1000
+	table := NewTable[int](1<<20, 0)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rank := int64(0); rank < ranks; rank++ {
+				table.Update(rank, 0, func(old int, ok bool) int {
+					return old + 1
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	for rank := int64(0); rank < ranks; rank++ {
+		if value, ok := table.Get(rank); ok && value != goroutines {
+			t.Fatalf("rank %d: %d updates; want %d", rank, value, goroutines)
+		}
+	}
+}
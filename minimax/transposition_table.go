@@ -6,14 +6,24 @@ package minimax
 //   * memoization of *mechanics.Game and *mechanics.LegalMoves
 //   * *TT.LegalMoves() and not *TT.setScore() initializes the *Interval stored in *TT
 //   * counters
+//
+// All tables have a fixed capacity within the Hash budget, v. *Table:
+//   * the memoization caches take a quarter; they are allocated once and shared by all searches
+//   * the intervals of the current and of the previous deepener iteration take an eighth each
+//   * MultiPV()'s table takes another eighth
+//   * the analysis results take a quarter, if shared, v. Cache
+// Searches running at the same time, e.g., concurrent web requests, add their interval tables.
 
 import (
+	"context"
 	"fmt"
+	"sankofa/book"
 	"sankofa/mech"
 	"sankofa/ow"
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,8 +31,12 @@ import (
 // DATA
 ////////////////////////////////////////////////////////////////
 
-// initial capacity of the transposition table
-const TT_CAP = 4096
+// estimated heap bytes referenced by the table values
+const (
+	INTERVAL_BYTES    = 32
+	POSITION_BYTES    = 32
+	LEGAL_MOVES_BYTES = 512
+)
 
 // dump transposition table when done
 var Dump bool
@@ -32,14 +46,32 @@ var Dump bool
 // DESIGN we implement method call APIs with mutexes and batch processing with channels
 type TT struct {
 	// the transposition table: *TT.tt[rank] ⇢ [α, β], i.e., a score range
-	tt map[int64]*Interval
+	tt *Table[*Interval]
 	// from previous iteration of the deepener
 	old *TT
+	// the next iteration, searched ahead by lazy-SMP helpers; nil if none
+	ahead *TT
 
 	// memoization of CPU-intensive evaluations
-	positions   map[int64]*mech.Position
-	legalMoves  map[int64]*mech.LegalMoves
-	movesInHand map[int64]int8
+	memo *memo
+	// results of earlier searches; nil if not shared
+	cache *Cache
+	// bottom-level evaluation
+	evaluator Evaluator
+	// when to stop the iterative deepener
+	limits Limits
+	// called after each completed deepener iteration; nil if none
+	progress Progress
+	// root entry of the opening book; nil if searched
+	book *book.Entry
+	// searched tree of the iteration; nil if not recorded
+	recorder *recorder
+	// MTD(f)'s last search: exact scores do not answer nodes with a wide window, v. MTDF()
+	principal bool
+
+	// move ordering: killer moves per ply, history per side and move; v. KillerMoves()
+	killers [][KILLERS]int8
+	history [2][mech.MOVE_CAP]int
 
 	// timers
 	globalTimeStamp    int64
@@ -54,23 +86,76 @@ type TT struct {
 	waitGroup *sync.WaitGroup // everybddy is done (parallel aspiration)
 	cntWg     int             // wait group counter
 
-	// timed out
-	cancelIteration chan struct{} // cancellation signal for an iteration
-	cancelDeepener  chan struct{} // cancellation signal for the iterative deepener
+	// cancellation: the iteration's context is derived from the deepener's, which is derived from the caller's
+	iteration       context.Context
+	cancelIteration context.CancelFunc
+	deepener        context.Context
+	cancelDeepener  context.CancelFunc
 
 	// counters
-	base           int // distance from bottom; ideally it should be 0
-	depth          int // depth of the current deepener iteration
-	visited        int // visied nodes: total
-	cumVisited     int // cumulative visited nodes (all iterations)
-	cntTt          int // retrievals from the transposition table
-	cntLegalMoves  int // retrievals from the legalMoves table
-	cntMovesInHand int // retrievals from the movesInHand table
-	cutOff         int // number of cutoffs
-	over           int // game over (won, starved or cycle)
-	database       int // number of bottom-level nodes using scores from the database
-	heuristic      int // number of bottom-level nodes evaluated using the heuristic
-	killed         int // number of interrupted goroutines
+	base        int // distance from bottom; ideally it should be 0
+	depth       int // depth of the current deepener iteration
+	visited     int // visied nodes: total
+	cumVisited  int // cumulative visited nodes (all iterations)
+	cntTt       int // retrievals from the transposition table
+	hits        int // nodes answered by the transposition table
+	cumHits     int // cumulative transposition table answers (all iterations)
+	refused     int // cumulative intervals refused for another history, v. history.go
+	cutOff      int // number of cutoffs
+	firstCutOff int // cutoffs by the first searched move
+	over        int // game over (won, starved or cycle)
+	database    int // number of bottom-level nodes using scores from the database
+	cumDatabase int // cumulative database scores (all iterations)
+	heuristic   int // number of bottom-level nodes evaluated using the heuristic
+	killed      int // number of interrupted goroutines
+	passes      int // MTD(f) null-window searches
+	researched  int // PVS full-window re-searches after a null-window fail-high
+	quiescence  int // bottom-level nodes extended by the quiescence search
+
+	// counters without the mutex
+	cntLegalMoves  atomic.Int64 // retrievals from the legalMoves table
+	cntMovesInHand atomic.Int64 // retrievals from the movesInHand table
+}
+
+// memoization caches; pure functions of the rank
+type memo struct {
+	positions   *Table[*mech.Position]
+	legalMoves  *Table[*mech.LegalMoves]
+	movesInHand *Table[int8]
+}
+
+// caches within a quarter of the Hash budget
+func newMemo() *memo {
+	bytes := Hash << 20
+	m := new(memo)
+	m.positions = NewTable[*mech.Position](bytes/32, POSITION_BYTES)
+	m.legalMoves = NewTable[*mech.LegalMoves](bytes*5/32, LEGAL_MOVES_BYTES)
+	m.movesInHand = NewTable[int8](bytes/16, 0)
+	return m
+}
+
+// memoization caches of all searches
+var memos struct {
+	mutex sync.Mutex
+	memo  *memo
+	hash  int // Hash when allocated
+}
+
+// the shared memoization caches; allocated on first use, again only if Hash changed
+func sharedMemo() *memo {
+	memos.mutex.Lock()
+	defer memos.mutex.Unlock()
+
+	if memos.memo == nil || memos.hash != Hash {
+		memos.memo = newMemo()
+		memos.hash = Hash
+	}
+	return memos.memo
+}
+
+// interval table of one deepener iteration; an eighth of the Hash budget
+func newIntervals() *Table[*Interval] {
+	return NewTable[*Interval]((Hash<<20)/8, INTERVAL_BYTES)
 }
 
 ////////////////////////////////////////////////////////////////
@@ -79,20 +164,24 @@ type TT struct {
 
 // the numer of stones on the board sets the α—β bandwidth
 func NewTT(game *mech.Game) *TT {
+	tt := newTT(game)
+	tt.tt = newIntervals()
+	tt.memo = sharedMemo()
+	return tt
+}
+
+// without tables
+func newTT(game *mech.Game) *TT {
 	tt := new(TT)
-	tt.tt = make(map[int64]*Interval, TT_CAP)
-	tt.positions = make(map[int64]*mech.Position, TT_CAP)
-	tt.legalMoves = make(map[int64]*mech.LegalMoves, TT_CAP)
-	tt.movesInHand = make(map[int64]int8, TT_CAP)
 
 	tt.globalTimeStamp = time.Now().UTC().UnixNano()
 	tt.iterationTimeStamp = time.Now().UTC().UnixNano()
 
 	tt.game = game
+	tt.evaluator = Evaluate
 	tt.waitGroup = new(sync.WaitGroup)
 
-	tt.cancelIteration = make(chan struct{})
-	tt.cancelDeepener = make(chan struct{})
+	tt.setDeepener(context.Background())
 
 	tt.depth = ow.MININT // monotonously incrementing
 	tt.base = ow.MAXINT  // monotonously decrementing
@@ -112,12 +201,19 @@ func (tt *TT) String() string {
 		", game-over: " + ow.Thousands(tt.over) +
 		" | depth: " + ow.Thousands(tt.depth-tt.base) +
 		", killed: " + ow.Thousands(tt.killed) +
-		" | TT: size: " + ow.Thousands(len(tt.tt)) +
+		", passes: " + ow.Thousands(tt.passes) +
+		", re-searched: " + ow.Thousands(tt.researched) +
+		", quiescence: " + ow.Thousands(tt.quiescence) +
+		", cut-offs: " + ow.Thousands(tt.cutOff) +
+		", first move: " + FirstMoveRate(tt.cutOff, tt.firstCutOff) +
+		" | TT: " + tt.tt.String() +
 		", #rd: " + ow.Thousands(tt.cntTt) +
-		" | LEGAL: size: " + ow.Thousands(len(tt.legalMoves)) +
-		", #rd: " + ow.Thousands(tt.cntLegalMoves) +
-		" | Δν: size: " + ow.Thousands(len(tt.movesInHand)) +
-		", #rd: " + ow.Thousands(tt.cntMovesInHand) +
+		", hits: " + ow.Thousands(tt.hits) +
+		", refused: " + ow.Thousands(tt.refused) +
+		" | LEGAL: " + tt.memo.legalMoves.String() +
+		", #rd: " + ow.Thousands(tt.cntLegalMoves.Load()) +
+		" | Δν: " + tt.memo.movesInHand.String() +
+		", #rd: " + ow.Thousands(tt.cntMovesInHand.Load()) +
 		" | " + strconv.FormatFloat((float64(time.Now().UTC().UnixNano())-float64(tt.iterationTimeStamp))/ow.GIGA64F, 'f', 2, 64) + " sec."
 
 	return r
@@ -146,6 +242,29 @@ func (tt *TT) Game() *mech.Game {
 	return r
 }
 
+// select the bottom-level evaluation; before Explore()
+func (tt *TT) SetEvaluator(evaluator Evaluator) *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.evaluator = evaluator
+	return tt
+}
+
+// bottom-level evaluation
+func (tt *TT) Evaluator() Evaluator {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	r := tt.evaluator
+	return r
+}
+
+// evaluate the current position of a game
+func (tt *TT) evaluate(game *mech.Game) int8 {
+	return tt.Evaluator().Evaluate(game)
+}
+
 // return the global time stamp
 func (tt *TT) Begin() int64 {
 	tt.mutex.Lock()
@@ -155,8 +274,9 @@ func (tt *TT) Begin() int64 {
 	return r
 }
 
-// add to a given partial score more partial information from a parallel aspiration thread
-func (tt *TT) save(rank int64, α, β, score, verdict int8) *TT {
+// add to a given partial score more partial information from a parallel aspiration thread;
+// depth is the remaining search depth below the rank
+func (tt *TT) save(rank int64, α, β, score, verdict int8, depth int, path path) *TT {
 	ow.Log("rank:", rank, ", α:", α, ", score:", score, ", β:", β, "verdict:", mech.VerdictToString(verdict))
 	if β < α {
 		ow.Panic("rank:", rank, "α=", α, " > β=", β)
@@ -169,22 +289,6 @@ func (tt *TT) save(rank int64, α, β, score, verdict int8) *TT {
 		ow.Panic("score:", score, "out of level:", level)
 	}
 
-	tt.mutex.Lock()
-	defer tt.mutex.Unlock()
-
-	// get it if available
-	old, ok := tt.tt[rank]
-	if !ok {
-		// if not, create one
-		old = NewInterval(rank, ow.MININT8, ow.MAXINT8, verdict)
-		ow.Log("initialize:", old)
-	}
-	if old.Scored() {
-		// already done; do not change
-		ow.Log("frozen")
-		return tt
-	}
-
 	var new *Interval
 	// update score
 	switch {
@@ -200,21 +304,44 @@ func (tt *TT) save(rank int64, α, β, score, verdict int8) *TT {
 		ow.Log("α < score < β")
 		new = NewInterval(rank, score, score, verdict)
 	}
+	new.path = path
+
+	// transaction on the rank's bucket
+	tt.tt.Update(rank, depth, func(old *Interval, ok bool) *Interval {
+		if !ok {
+			// if not, create one
+			old = NewInterval(rank, ow.MININT8, ow.MAXINT8, verdict)
+			ow.Log("initialize:", old)
+		}
+		if old.path != new.path {
+			// another history, or a dependence on it found deeper: start afresh
+			ow.Log("rank:", rank, "other path:", old, "⇢", new)
+			return new
+		}
+		if old.Scored() {
+			// already done; do not change
+			ow.Log("frozen")
+			return old
+		}
+
+		r := new
+		if old.Disjoint(new) {
+			ow.Log("rank:", rank, "disjoint")
+		} else {
+			r = old.Intersect(new)
+			r.path = path
+		}
+
+		ow.Log("rank:", rank, ": old:", old, "⋂ new:", new, "⇢", r)
+		return r
+	})
 
-	if old.Disjoint(new) {
-		ow.Log("rank:", rank, "disjoint")
-		tt.tt[rank] = new
-	} else {
-		tt.tt[rank] = old.Intersect(new)
-	}
-
-	ow.Log("rank:", rank, ": old:", old, "⋂ new:", new, "⇢", tt.tt[rank])
 	return tt
 }
 
 // no-lock: do we have a record for the given score?
 func (tt *TT) _known(rank int64) bool {
-	_, ok := tt.tt[rank]
+	_, ok := tt.tt.Get(rank)
 	return ok
 }
 
@@ -228,7 +355,7 @@ func (tt *TT) Known(rank int64) bool {
 
 // no-lock: find score interval in transposition table
 func (tt *TT) _interval(rank int64) *Interval {
-	interval, ok := tt.tt[rank]
+	interval, ok := tt.tt.Get(rank)
 	if ok {
 		tt.cntTt += 1
 		return interval
@@ -245,6 +372,12 @@ func (tt *TT) Interval(rank int64) *Interval {
 	return tt._interval(rank)
 }
 
+// does the stored interval fall outside the α—β window?
+func bounded(interval *Interval, α, β int8) bool {
+	_, ok := interval.Bound(α, β)
+	return ok
+}
+
 // dump the contents of TT to STDOUT for tracing purposes
 func (tt *TT) Dump() *TT {
 	fmt.Println(tt)
@@ -255,9 +388,11 @@ func (tt *TT) Dump() *TT {
 	// extract keys
 	// must do some anaerobics (int⇢int⇢int64) because of sortSlice() accepting only ints
 	ranks := make([]int, 0)
-	for rank := range tt.tt {
+	intervals := make(map[int64]*Interval)
+	tt.tt.Range(func(rank int64, depth int, interval *Interval) {
 		ranks = append(ranks, int(rank))
-	}
+		intervals[rank] = interval
+	})
 
 	// sort keys
 	sort.Slice(ranks, func(a, b int) bool {
@@ -266,7 +401,7 @@ func (tt *TT) Dump() *TT {
 
 	// output intervals
 	for _, r := range ranks {
-		fmt.Println(r, "⇢", tt.tt[int64(r)])
+		fmt.Println(r, "⇢", intervals[int64(r)])
 	}
 
 	return tt
@@ -311,13 +446,12 @@ func (tt *TT) Wait() *TT {
 
 // no-lock: request everyone to stop working
 func (tt *TT) _abortIteration() *TT {
-	select {
-	case <-tt.cancelIteration:
+	if tt.iteration.Err() != nil {
 		// BENIGN
 		// this might happen, since the sequence IsDone()...Done() is not continuosly locked.
 		ow.Log("too late")
-	default:
-		close(tt.cancelIteration)
+	} else {
+		tt.cancelIteration()
 		// still running workers will be killed
 		tt.killed = tt.cntWg
 	}
@@ -335,12 +469,7 @@ func (tt *TT) AbortIteration() *TT {
 
 // no-lock: are we done here?
 func (tt *TT) _iterationAborted() bool {
-	select {
-	case <-tt.cancelIteration:
-		return true
-	default:
-		return false
-	}
+	return tt.iteration.Err() != nil
 }
 
 // are we done here?
@@ -351,50 +480,121 @@ func (tt *TT) IterationAborted() bool {
 	return tt._iterationAborted()
 }
 
+// no-lock: request the iterative deepener to stop
+func (tt *TT) _abortDeepener() *TT {
+	tt.cancelDeepener()
+	return tt
+}
+
+// request the iterative deepener to stop
 func (tt *TT) AbortDeepener() *TT {
 	tt.mutex.Lock()
 	defer tt.mutex.Unlock()
 
-	select {
-	case <-tt.cancelDeepener:
-		// BENIGN
-		// this might happen, since the sequence IsDone()...Done() is not continuosly locked.
-		ow.Log("too late")
-	default:
-		close(tt.cancelDeepener)
-	}
+	return tt._abortDeepener()
+}
+
+func (tt *TT) DeepenerAborted() bool {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	return tt.deepener.Err() != nil
+}
 
+// no-lock: contexts of the iterative deepener and of its first iteration, derived from the caller's context
+func (tt *TT) setDeepener(ctx context.Context) *TT {
+	tt.deepener, tt.cancelDeepener = context.WithCancel(ctx)
+	tt.iteration, tt.cancelIteration = context.WithCancel(tt.deepener)
 	return tt
 }
 
-func (tt *TT) DeepenerAborted() bool {
+// context of the current iteration
+func (tt *TT) iterationContext() context.Context {
 	tt.mutex.RLock()
 	defer tt.mutex.RUnlock()
 
-	select {
-	case <-tt.cancelDeepener:
-		return true
-	default:
-		return false
-	}
+	r := tt.iteration
+	return r
 }
 
-// restart synchronization for another deepener iteration
+// restart synchronization for another deepener iteration;
+// recycles the interval table of the iteration before the previous one
 func (tt *TT) Restart() *TT {
 	ow.Log("restart")
 
-	r := NewTT(tt.game)
-
 	tt.mutex.Lock()
 	defer tt.mutex.Unlock()
 
+	var intervals *Table[*Interval]
+	if tt.old != nil {
+		intervals = tt.old.tt.Clear()
+		tt.old = nil
+	} else {
+		intervals = newIntervals()
+	}
+	base := ow.MAXINT
+	if tt.ahead != nil {
+		// as from the cache, the root and its successors are searched afresh, v. cached();
+		// the bottom reached ahead is reached by the next iteration
+		tt.ahead.tt.Range(func(rank int64, draft int, interval *Interval) {
+			if draft < tt.depth {
+				intervals.Put(rank, draft, interval)
+			}
+		})
+		base = tt.ahead.Base()
+		tt.ahead = nil
+	}
+	r := tt._follow(intervals)
+	r.base = base
+	return r
+}
+
+// no-lock: the intervals of the helpers that searched one ply deeper start the next iteration; their nodes count
+func (tt *TT) _searchedAhead(ahead *TT) {
+	ahead.mutex.RLock()
+	defer ahead.mutex.RUnlock()
+
+	tt.ahead = ahead
+	tt.visited += ahead.visited
+	tt.cumVisited += ahead.visited
+}
+
+// a table of its own that follows this one, as Restart() does, without recycling or modifying it
+func (tt *TT) fork() *TT {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	return tt._follow(newIntervals())
+}
+
+// no-lock: the next table, with the given intervals; this one becomes its previous iteration
+func (tt *TT) _follow(intervals *Table[*Interval]) *TT {
+	r := newTT(tt.game)
+	r.tt = intervals
 	r.old = tt
-	r.cancelDeepener = tt.cancelDeepener
+	r.deepener, r.cancelDeepener = tt.deepener, tt.cancelDeepener
+	r.iteration, r.cancelIteration = context.WithCancel(r.deepener)
 	r.depth = tt.depth
-	r.legalMoves = tt.legalMoves
-	r.movesInHand = tt.movesInHand
+	r.memo = tt.memo
+	r.cache = tt.cache
+	r.evaluator = tt.evaluator
+	r.limits = tt.limits
+	r.progress = tt.progress
+	if tt.recorder != nil {
+		r.recorder = newRecorder(tt.recorder.tree.Plies)
+	}
+	r.killers = append(r.killers, tt.killers...)
+	// older cut-offs weigh less
+	for side := range tt.history {
+		for move := range tt.history[side] {
+			r.history[side][move] = tt.history[side][move] / 2
+		}
+	}
 	r.globalTimeStamp = tt.globalTimeStamp
 	r.cumVisited = tt.cumVisited
+	r.cumDatabase = tt.cumDatabase
+	r.cumHits = tt.cumHits
+	r.refused = tt.refused
 
 	return r
 }
@@ -445,6 +645,9 @@ func (tt *TT) incVisited() *TT {
 
 	tt.visited++
 	tt.cumVisited++
+	if tt.limits.Nodes > 0 && tt.cumVisited >= tt.limits.Nodes {
+		tt._abortDeepener()
+	}
 	return tt
 }
 
@@ -461,9 +664,37 @@ func (tt *TT) incDatabase() *TT {
 	defer tt.mutex.Unlock()
 
 	tt.database++
+	tt.cumDatabase++
 	return tt
 }
 
+// cumulative bottom-level scores from the database (all iterations)
+func (tt *TT) Database() int {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	aux := tt.cumDatabase
+	return aux
+}
+
+func (tt *TT) incHits() *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.hits++
+	tt.cumHits++
+	return tt
+}
+
+// cumulative nodes answered by the transposition table (all iterations)
+func (tt *TT) Hits() int {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	aux := tt.cumHits
+	return aux
+}
+
 func (tt *TT) incHeuristic() *TT {
 	tt.mutex.Lock()
 	defer tt.mutex.Unlock()
@@ -472,10 +703,65 @@ func (tt *TT) incHeuristic() *TT {
 	return tt
 }
 
-func (tt *TT) incCutOff() *TT {
+// cut-offs of the current iteration: all, and those by the first searched move
+func (tt *TT) CutOffs() (all, first int) {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	return tt.cutOff, tt.firstCutOff
+}
+
+// share of the cut-offs by the first searched move; a measure of move ordering
+func FirstMoveRate(all, first int) string {
+	if all == 0 {
+		return "-"
+	}
+	return strconv.FormatFloat(100*float64(first)/float64(all), 'f', 1, 64) + "%"
+}
+
+func (tt *TT) incResearched() *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.researched++
+	return tt
+}
+
+func (tt *TT) incQuiescence() *TT {
+	tt.mutex.Lock()
+	defer tt.mutex.Unlock()
+
+	tt.quiescence++
+	return tt
+}
+
+// bottom-level nodes extended by the quiescence search
+func (tt *TT) Quiescence() int {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	r := tt.quiescence
+	return r
+}
+
+func (tt *TT) incPasses() *TT {
 	tt.mutex.Lock()
 	defer tt.mutex.Unlock()
 
-	tt.cutOff++
+	tt.passes++
 	return tt
 }
+
+// cumulative visited nodes (all iterations)
+func (tt *TT) Nodes() int {
+	tt.mutex.RLock()
+	defer tt.mutex.RUnlock()
+
+	aux := tt.cumVisited
+	return aux
+}
+
+// seconds since the search began
+func (tt *TT) Elapsed() float64 {
+	return float64(time.Now().UTC().UnixNano()-tt.Begin()) / ow.GIGA64F
+}
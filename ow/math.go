@@ -41,6 +41,15 @@ const GIGA64F = float64(GIGA)
 // random number generator
 var Rng = rand.New(rand.NewSource(time.Now().UTC().UnixNano()))
 
+// seed of the random number generator; 0: time-based
+var Seed int64
+
+// reproducible random numbers: the same sequence for the same seed
+func Reseed(seed int64) {
+	Seed = seed
+	Rng = rand.New(rand.NewSource(seed))
+}
+
 // is this integer even?
 func Even[N ~int8 | ~int64 | ~int](i N) bool {
 	return i%2 == 0
@@ -0,0 +1,162 @@
+package scc
+
+// arrays of 32-bit local indices, either in memory or spilled to disk
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"sankofa/ow"
+)
+
+// bytes of RAM the in-memory arrays may take; beyond, they are spilled to disk
+var MemoryLimit = int64(4) << 30
+
+// directory for the external-memory arrays
+var TempDir = os.TempDir()
+
+// uint32 entries per disk page
+const PAGE = 1 << 14
+
+// maximum number of cached disk pages per array
+const PAGES = 1 << 10
+
+// an array of 32-bit local indices
+type array interface {
+	Get(i uint32) uint32
+	Set(i, value uint32)
+	Close()
+}
+
+// allocate in memory if the budget allows it, otherwise on disk;
+// count is the number of arrays of this size that are needed together,
+// reserved the bytes of RAM they share the budget with, e.g., bit maps;
+// the page caches of spilled arrays share what is left of the budget, at least one page each
+func newArray(size uint32, count, reserved int64) array {
+	if int64(size)*4*count+reserved <= MemoryLimit {
+		ow.Log("in memory:", size)
+		return make(memArray, size)
+	}
+	pages := ow.Max(1, ow.Min(PAGES, (MemoryLimit-reserved)/count/(PAGE*4)))
+	ow.Log("external memory:", size, "in:", TempDir, "cached pages:", pages)
+	return newFileArray(int(pages))
+}
+
+// a stack of 32-bit local indices on top of an array
+type arrayStack struct {
+	array
+	size uint32
+}
+
+func (stack *arrayStack) Len() uint32 {
+	return stack.size
+}
+
+func (stack *arrayStack) Push(value uint32) {
+	stack.Set(stack.size, value)
+	stack.size++
+}
+
+func (stack *arrayStack) Pop() uint32 {
+	stack.size--
+	return stack.Get(stack.size)
+}
+
+// the element i places below the top
+func (stack *arrayStack) Peek(i uint32) uint32 {
+	return stack.Get(stack.size - 1 - i)
+}
+
+////////////////////////////////////////////////////////////////
+// IN MEMORY
+////////////////////////////////////////////////////////////////
+
+type memArray []uint32
+
+func (a memArray) Get(i uint32) uint32 {
+	return a[i]
+}
+
+func (a memArray) Set(i, value uint32) {
+	a[i] = value
+}
+
+func (a memArray) Close() {
+}
+
+////////////////////////////////////////////////////////////////
+// EXTERNAL MEMORY
+////////////////////////////////////////////////////////////////
+
+// a cached disk page
+type page struct {
+	number int64 // page number; -1 if empty
+	dirty  bool
+	data   []byte
+}
+
+// a temporary file behind a direct-mapped page cache
+type fileArray struct {
+	file  *os.File
+	pages []page
+}
+
+// with a cache of the given number of pages
+func newFileArray(pages int) *fileArray {
+	file, err := os.CreateTemp(TempDir, "scc-*.tmp")
+	ow.Check(err)
+
+	a := new(fileArray)
+	a.file = file
+	a.pages = make([]page, pages)
+	for i := range a.pages {
+		a.pages[i].number = -1
+		a.pages[i].data = make([]byte, PAGE*4)
+	}
+	return a
+}
+
+// cached page holding entry i; swaps pages as needed
+func (a *fileArray) page(i uint32) *page {
+	number := int64(i / PAGE)
+	p := &a.pages[number%int64(len(a.pages))]
+	if p.number == number {
+		return p
+	}
+
+	// write back
+	if p.dirty {
+		_, err := a.file.WriteAt(p.data, p.number*PAGE*4)
+		ow.Check(err)
+	}
+
+	// read; never written pages are zeroes
+	n, err := a.file.ReadAt(p.data, number*PAGE*4)
+	if err != io.EOF {
+		ow.Check(err)
+	}
+	clear(p.data[n:])
+
+	p.number = number
+	p.dirty = false
+	return p
+}
+
+func (a *fileArray) Get(i uint32) uint32 {
+	offset := (i % PAGE) * 4
+	return binary.LittleEndian.Uint32(a.page(i).data[offset:])
+}
+
+func (a *fileArray) Set(i, value uint32) {
+	offset := (i % PAGE) * 4
+	p := a.page(i)
+	binary.LittleEndian.PutUint32(p.data[offset:], value)
+	p.dirty = true
+}
+
+// remove the temporary file
+func (a *fileArray) Close() {
+	name := a.file.Name()
+	ow.Check(a.file.Close())
+	ow.Check(os.Remove(name))
+}
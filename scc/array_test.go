@@ -0,0 +1,49 @@
+package scc
+
+import (
+	"testing"
+)
+
+// spilled arrays keep their page caches within the memory limit, one page at least
+func TestArrayPages(t *testing.T) {
+	defer func(limit int64, dir string) { MemoryLimit, TempDir = limit, dir }(MemoryLimit, TempDir)
+	TempDir = t.TempDir()
+
+	const SIZE = 1 << 30 // 4 GB per array: always spilled
+	for limit, want := range map[int64]int{
+		0:                         1,
+		10 * PAGE * 4:             1,
+		100 * PAGE * 4:            9, // (100 - 10 reserved) / 10 arrays
+		int64(20) << 30:           PAGES,
+		(5*PAGES + 10) * PAGE * 4: PAGES / 2,
+	} {
+		MemoryLimit = limit
+		a := newArray(SIZE, 10, 10*PAGE*4)
+		if got := len(a.(*fileArray).pages); got != want {
+			t.Errorf("limit %d: %d pages; want %d", limit, got, want)
+		}
+		a.Close()
+	}
+}
+
+// pages swapped out of a small cache are read back
+func TestFileArray(t *testing.T) {
+	defer func(dir string) { TempDir = dir }(TempDir)
+	TempDir = t.TempDir()
+
+	a := newFileArray(2)
+	defer a.Close()
+	const SIZE = 5 * PAGE
+	for i := uint32(0); i < SIZE; i += 7 {
+		a.Set(i, i^0xdeadbeef)
+	}
+	for i := uint32(0); i < SIZE; i++ {
+		want := uint32(0)
+		if i%7 == 0 {
+			want = i ^ 0xdeadbeef
+		}
+		if got := a.Get(i); got != want {
+			t.Fatalf("%d: %#x; want %#x", i, got, want)
+		}
+	}
+}
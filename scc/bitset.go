@@ -0,0 +1,31 @@
+package scc
+
+// packed bit maps; one bit per position of a level
+
+// a fixed-size bit map, 64 bits per word
+type Bitset []uint64
+
+// all bits cleared
+func NewBitset(size uint32) Bitset {
+	return make(Bitset, (uint64(size)+63)/64)
+}
+
+// is bit i set?
+func (bitset Bitset) Bit(i uint32) bool {
+	return bitset[i>>6]&(1<<(i&63)) != 0
+}
+
+// set bit i
+func (bitset Bitset) Set(i uint32) {
+	bitset[i>>6] |= 1 << (i & 63)
+}
+
+// clear bit i
+func (bitset Bitset) Clear(i uint32) {
+	bitset[i>>6] &^= 1 << (i & 63)
+}
+
+// size in bytes
+func (bitset Bitset) Bytes() int64 {
+	return int64(len(bitset)) * 8
+}
@@ -0,0 +1,403 @@
+package scc
+
+// persistent catalogue of the strongly connected components on each level
+//
+// File format, one file per level, little endian:
+//   - magic "SCC2", level (1 byte), number of SCCs (4 bytes), number of members of all SCCs (4 bytes)
+//   - for each SCC: number of members (4 bytes), member ranks (8 bytes each, sorted)
+//   - member index, sorted by rank: rank (8 bytes), SCC ID (4 bytes), file offset of the SCC (8 bytes)
+//
+// SCCs are sorted by their smallest member; the IDs 1, 2, ... follow this order.
+// Thus, Tarjan() and Parallel() produce the same catalogue.
+//
+// Find() looks a rank up by binary search in the member index and reads only its SCC.
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path"
+	"sankofa/ow"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// directory of the catalogue files
+var Dir string
+
+// file header
+const MAGIC = "SCC2"
+
+// bytes of the header: magic, level, number of SCCs and of members
+const HEADER = len(MAGIC) + 1 + 4 + 4
+
+// bytes of a member index entry: rank, SCC ID, file offset
+const INDEX_ENTRY = 8 + 4 + 8
+
+func init() {
+	// default path to the catalogue directory, next to the database
+	user, err := user.Current()
+	ow.Check(err)
+	Dir = path.Clean(path.Join(user.HomeDir, "oware.scc"))
+}
+
+// a strongly connected component: positions that may cycle with each other
+type Component struct {
+	ID      int
+	Level   int8
+	Members []int64 // sorted ranks
+}
+
+// all SCCs of a level
+type Catalogue struct {
+	Level      int8
+	Components []*Component
+	member     map[int64]*Component // rank ⇢ SCC
+}
+
+// sort and number the SCCs found by Tarjan() or Parallel()
+func NewCatalogue(level int8, lists [][]int64) *Catalogue {
+	catalogue := new(Catalogue)
+	catalogue.Level = level
+	catalogue.Components = make([]*Component, 0, len(lists))
+	catalogue.member = make(map[int64]*Component)
+
+	for _, list := range lists {
+		component := new(Component)
+		component.Level = level
+		component.Members = make([]int64, len(list))
+		copy(component.Members, list)
+		sort.Slice(component.Members, func(a, b int) bool {
+			return component.Members[a] < component.Members[b]
+		})
+		catalogue.Components = append(catalogue.Components, component)
+	}
+
+	sort.Slice(catalogue.Components, func(a, b int) bool {
+		return catalogue.Components[a].Members[0] < catalogue.Components[b].Members[0]
+	})
+	for i, component := range catalogue.Components {
+		component.ID = i + 1
+		for _, rank := range component.Members {
+			catalogue.member[rank] = component
+		}
+	}
+
+	return catalogue
+}
+
+func (component *Component) String() string {
+	return fmt.Sprint("#", component.ID, " (", len(component.Members), " positions)")
+}
+
+// the other members of the SCC; positions that the given rank may cycle with
+func (component *Component) Cycles(rank int64) []int64 {
+	r := make([]int64, 0, len(component.Members)-1)
+	for _, member := range component.Members {
+		if member != rank {
+			r = append(r, member)
+		}
+	}
+	return r
+}
+
+// the SCC the rank belongs to; nil if none
+func (catalogue *Catalogue) Component(rank int64) *Component {
+	return catalogue.member[rank]
+}
+
+// all members of all SCCs
+func (catalogue *Catalogue) Members() []int64 {
+	r := make([]int64, 0, len(catalogue.member))
+	for _, component := range catalogue.Components {
+		r = append(r, component.Members...)
+	}
+	return r
+}
+
+// SCCs as lists of members
+func (catalogue *Catalogue) Lists() [][]int64 {
+	r := make([][]int64, 0, len(catalogue.Components))
+	for _, component := range catalogue.Components {
+		r = append(r, component.Members)
+	}
+	return r
+}
+
+////////////////////////////////////////////////////////////////
+// PERSISTENCE
+////////////////////////////////////////////////////////////////
+
+// catalogue file for a level
+func FileName(level int8) string {
+	return path.Join(Dir, fmt.Sprintf("level-%02d.scc", level))
+}
+
+// write to the catalogue directory; panics on error
+func (catalogue *Catalogue) Save() {
+	ow.Check(os.MkdirAll(Dir, 0755))
+	file, err := os.Create(FileName(catalogue.Level))
+	ow.Check(err)
+	defer file.Close()
+	ow.Log("save:", file.Name(), "SCCs:", len(catalogue.Components))
+
+	writer := bufio.NewWriter(file)
+	_, err = writer.WriteString(MAGIC)
+	ow.Check(err)
+	ow.Check(binary.Write(writer, binary.LittleEndian, catalogue.Level))
+	ow.Check(binary.Write(writer, binary.LittleEndian, uint32(len(catalogue.Components))))
+	ow.Check(binary.Write(writer, binary.LittleEndian, uint32(len(catalogue.member))))
+
+	index := make([]indexEntry, 0, len(catalogue.member))
+	offset := uint64(HEADER)
+	for _, component := range catalogue.Components {
+		ow.Check(binary.Write(writer, binary.LittleEndian, uint32(len(component.Members))))
+		ow.Check(binary.Write(writer, binary.LittleEndian, component.Members))
+		for _, rank := range component.Members {
+			index = append(index, indexEntry{rank, uint32(component.ID), offset})
+		}
+		offset += 4 + 8*uint64(len(component.Members))
+	}
+
+	sort.Slice(index, func(a, b int) bool {
+		return index[a].Rank < index[b].Rank
+	})
+	ow.Check(binary.Write(writer, binary.LittleEndian, index))
+	ow.Check(writer.Flush())
+}
+
+// an entry of the member index
+type indexEntry struct {
+	Rank   int64
+	ID     uint32
+	Offset uint64
+}
+
+// read from the catalogue directory; false if there is no catalogue for this level, or if it is corrupt
+func Load(level int8) (*Catalogue, bool) {
+	file, err := os.Open(FileName(level))
+	if err != nil {
+		// the catalogue is optional
+		ow.Log("no catalogue:", err)
+		return nil, false
+	}
+	defer file.Close()
+
+	lists, err := read(bufio.NewReader(file), level)
+	if err != nil {
+		fmt.Println("corrupt SCC catalogue:", file.Name(), err)
+		return nil, false
+	}
+
+	ow.Log("loaded:", file.Name(), "SCCs:", len(lists))
+	return NewCatalogue(level, lists), true
+}
+
+// the SCCs of a catalogue file
+func read(reader io.Reader, level int8) ([][]int64, error) {
+	magic := make([]byte, len(MAGIC))
+	if _, err := io.ReadFull(reader, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != MAGIC {
+		return nil, fmt.Errorf("not an SCC catalogue")
+	}
+
+	var stored int8
+	var count, members uint32
+	if err := binary.Read(reader, binary.LittleEndian, &stored); err != nil {
+		return nil, err
+	}
+	if stored != level {
+		return nil, fmt.Errorf("holds level: %d", stored)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &members); err != nil {
+		return nil, err
+	}
+
+	// no more members than positions on the level
+	positions := levelSize(level)
+	if int64(count) > positions || int64(members) > positions {
+		return nil, fmt.Errorf("SCCs: %d, members: %d", count, members)
+	}
+
+	lists := make([][]int64, count)
+	offsets := make(map[uint64]uint32, count) // file offset ⇢ SCC ID
+	offset := uint64(HEADER)
+	var total int64
+	for i := range lists {
+		var size uint32
+		if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+		if size < 2 || int64(size) > positions {
+			return nil, fmt.Errorf("SCC: %d, members: %d", i+1, size)
+		}
+		lists[i] = make([]int64, size)
+		if err := binary.Read(reader, binary.LittleEndian, lists[i]); err != nil {
+			return nil, err
+		}
+		offsets[offset] = uint32(i + 1)
+		offset += 4 + 8*uint64(size)
+		total += int64(size)
+	}
+	if total != int64(members) {
+		return nil, fmt.Errorf("members: %d, in the SCCs: %d", members, total)
+	}
+
+	// the index points at the SCCs, in rank order
+	index := make([]indexEntry, members)
+	if err := binary.Read(reader, binary.LittleEndian, index); err != nil {
+		return nil, err
+	}
+	for i, entry := range index {
+		if offsets[entry.Offset] != entry.ID || entry.ID == 0 || (i > 0 && entry.Rank <= index[i-1].Rank) {
+			return nil, fmt.Errorf("index entry: %d", i)
+		}
+	}
+	return lists, nil
+}
+
+// number of positions on a level
+func levelSize(level int8) int64 {
+	r := ow.LevelUpperLimits[level] + 1
+	if level > 0 {
+		r -= ow.LevelUpperLimits[level-1] + 1
+	}
+	return r
+}
+
+////////////////////////////////////////////////////////////////
+// QUERIES
+////////////////////////////////////////////////////////////////
+
+// catalogue files by level, each opened once on first use; nil if not available
+var catalogues [len(ow.LevelUpperLimits)]struct {
+	once  sync.Once
+	index *memberIndex
+}
+
+// the member index of an open catalogue file
+type memberIndex struct {
+	file    *os.File
+	level   int8
+	members int64 // number of index entries
+	offset  int64 // of the first index entry
+}
+
+// open a catalogue file for queries; false if there is no catalogue for this level, or if its header is corrupt
+func openIndex(level int8) (*memberIndex, bool) {
+	file, err := os.Open(FileName(level))
+	if err != nil {
+		ow.Log("no catalogue:", err)
+		return nil, false
+	}
+
+	members, offset, err := header(file, level)
+	if err != nil {
+		fmt.Println("corrupt SCC catalogue:", file.Name(), err)
+		file.Close()
+		return nil, false
+	}
+
+	ow.Log("opened:", file.Name(), "members:", members)
+	return &memberIndex{file, level, members, offset}, true
+}
+
+// number of members and offset of the member index, from the header and the file size
+func header(file *os.File, level int8) (members, offset int64, err error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	buffer := make([]byte, HEADER)
+	if _, err := file.ReadAt(buffer, 0); err != nil {
+		return 0, 0, err
+	}
+	if string(buffer[:len(MAGIC)]) != MAGIC || int8(buffer[len(MAGIC)]) != level {
+		return 0, 0, fmt.Errorf("not an SCC catalogue of level %d", level)
+	}
+	members = int64(binary.LittleEndian.Uint32(buffer[HEADER-4:]))
+	offset = info.Size() - members*INDEX_ENTRY
+	if members > levelSize(level) || offset < int64(HEADER) {
+		return 0, 0, fmt.Errorf("members: %d", members)
+	}
+	return members, offset, nil
+}
+
+// index entry i
+func (index *memberIndex) entry(i int64) (indexEntry, error) {
+	var entry indexEntry
+	section := io.NewSectionReader(index.file, index.offset+i*INDEX_ENTRY, INDEX_ENTRY)
+	err := binary.Read(section, binary.LittleEndian, &entry)
+	return entry, err
+}
+
+// binary search in the member index, then read the SCC; nil if the rank is not a member
+func (index *memberIndex) find(rank int64) (*Component, error) {
+	low, high := int64(0), index.members
+	for low < high {
+		middle := low + (high-low)/2
+		entry, err := index.entry(middle)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case entry.Rank < rank:
+			low = middle + 1
+		case entry.Rank > rank:
+			high = middle
+		default:
+			return index.component(entry)
+		}
+	}
+	return nil, nil
+}
+
+// the SCC an index entry points at
+func (index *memberIndex) component(entry indexEntry) (*Component, error) {
+	reader := io.NewSectionReader(index.file, int64(entry.Offset), index.offset-int64(entry.Offset))
+	var size uint32
+	if err := binary.Read(reader, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 2 || int64(size) > index.members {
+		return nil, fmt.Errorf("SCC: %d, members: %d", entry.ID, size)
+	}
+	component := &Component{ID: int(entry.ID), Level: index.level, Members: make([]int64, size)}
+	if err := binary.Read(reader, binary.LittleEndian, component.Members); err != nil {
+		return nil, err
+	}
+	if _, ok := slices.BinarySearch(component.Members, entry.Rank); !ok {
+		return nil, fmt.Errorf("SCC: %d, not a member: %d", entry.ID, entry.Rank)
+	}
+	return component, nil
+}
+
+// the SCC a rank belongs to; nil if none or if its level has no catalogue;
+// only the SCC is read from the file, v. the member index
+func Find(rank int64) *Component {
+	level := ow.Level(rank)
+
+	// other levels are not blocked while opening
+	entry := &catalogues[level]
+	entry.once.Do(func() {
+		entry.index, _ = openIndex(level)
+	})
+	if entry.index == nil {
+		return nil
+	}
+	component, err := entry.index.find(rank)
+	if err != nil {
+		fmt.Println("corrupt SCC catalogue:", entry.index.file.Name(), err)
+		return nil
+	}
+	return component
+}
@@ -0,0 +1,98 @@
+package scc
+
+import (
+	"os"
+	"reflect"
+	"sankofa/ow"
+	"sync"
+	"testing"
+)
+
+// SCC2 files: the catalogue is read back as saved
+func TestCatalogueRoundTrip(t *testing.T) {
+	defer func(dir string) { Dir = dir }(Dir)
+	Dir = t.TempDir()
+
+	for level := int8(1); level <= TEST_LEVELS; level++ {
+		saved := NewCatalogue(level, Tarjan(level))
+		saved.Save()
+		loaded, ok := Load(level)
+		if !ok {
+			t.Fatalf("level %d: not loaded", level)
+		}
+		if !reflect.DeepEqual(saved.Lists(), loaded.Lists()) {
+			t.Errorf("level %d: saved %d SCCs, loaded %d", level, len(saved.Components), len(loaded.Components))
+		}
+		for _, rank := range saved.Members() {
+			if saved.Component(rank).ID != loaded.Component(rank).ID {
+				t.Errorf("level %d, rank %d: SCC %v, loaded %v", level, rank, saved.Component(rank), loaded.Component(rank))
+			}
+		}
+	}
+}
+
+// missing, truncated and foreign files are not found
+func TestCatalogueCorrupt(t *testing.T) {
+	defer func(dir string) { Dir = dir }(Dir)
+	Dir = t.TempDir()
+
+	const level = TEST_LEVELS
+	if _, ok := Load(level); ok {
+		t.Error("missing file loaded")
+	}
+
+	NewCatalogue(level, Tarjan(level)).Save()
+	content, err := os.ReadFile(FileName(level))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, corrupt := range map[string][]byte{
+		"truncated":     content[:len(content)-3],
+		"header only":   content[:len(MAGIC)+1],
+		"foreign":       append([]byte("BOOK1"), content[len(MAGIC):]...),
+		"another level": append(append([]byte(MAGIC), level+1), content[len(MAGIC)+1:]...),
+		"huge count":    append(append([]byte(MAGIC), level), 0xff, 0xff, 0xff, 0xff),
+	} {
+		if err := os.WriteFile(FileName(level), corrupt, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := Load(level); ok {
+			t.Errorf("%s file loaded", name)
+		}
+	}
+}
+
+// Find() reads the SCC of a member from the file, as saved; other ranks have none
+func TestFind(t *testing.T) {
+	defer func(dir string) { Dir = dir }(Dir)
+	Dir = t.TempDir()
+	forget := func() {
+		for level := range catalogues {
+			if index := catalogues[level].index; index != nil {
+				index.file.Close()
+			}
+			catalogues[level] = struct {
+				once  sync.Once
+				index *memberIndex
+			}{}
+		}
+	}
+	forget()
+	defer forget()
+
+	for level := int8(1); level <= TEST_LEVELS; level++ {
+		saved := NewCatalogue(level, Tarjan(level))
+		saved.Save()
+		for rank := ow.LevelUpperLimits[level-1] + 1; rank <= ow.LevelUpperLimits[level]; rank++ {
+			found, want := Find(rank), saved.Component(rank)
+			if (found == nil) != (want == nil) || (found != nil && !reflect.DeepEqual(*found, *want)) {
+				t.Fatalf("level %d, rank %d: %v; want %v", level, rank, found, want)
+			}
+		}
+	}
+
+	// no catalogue
+	if component := Find(ow.LevelUpperLimits[TEST_LEVELS+1]); component != nil {
+		t.Errorf("no catalogue: %v", component)
+	}
+}
@@ -0,0 +1,298 @@
+package scc
+
+// Find all members of strongly-connected components on a given level, in parallel.
+//
+// Implements trimming followed by the colouring algorithm of Orzan:
+//   - trim: vertices without a live predecessor or successor are one-man SCCs; repeat until stable.
+//   - colour: each live vertex starts with its own local index as colour;
+//     the maximum colour is propagated forward along the edges until stable.
+//     Then, each vertex is coloured by the largest vertex that reaches it.
+//   - roots: a vertex whose colour equals its own index is a root.
+//     Its SCC consists of the vertices of the same colour that reach the root backwards.
+//     Different roots have disjoint colours and are processed concurrently.
+//   - the SCCs found are removed; trim and repeat with the remaining vertices.
+//
+// All phases are split among goroutines.
+// The removed flags are atomic: a backward search owns the vertices of its colour,
+// while the other phases only read them between the rounds.
+// The same-level move graph is held in memory: successors and predecessors in compressed arrays.
+// Its size limits the useable levels similarly to Tarjan.
+
+import (
+	"sankofa/mech"
+	"sankofa/ow"
+	"sync"
+	"sync/atomic"
+)
+
+// vertices per batch handed to a goroutine
+const CHUNK = 1 << 12
+
+// the same-level move graph in compressed sparse row format
+type graph struct {
+	size       uint32
+	outDegree  []uint8
+	successors []uint32 // mech.MOVE_CAP slots per vertex
+	offsets    []uint32 // predecessors of v: predecessors[offsets[v]:offsets[v+1]]
+	predecs    []uint32
+}
+
+// run f on [from, to) batches of [0, size) using goroutines
+func parallel(goroutines int, size uint32, f func(from, to uint32)) {
+	var waitGroup sync.WaitGroup
+	var next atomic.Uint64
+
+	for i := 0; i < goroutines; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			for {
+				from := next.Add(CHUNK) - CHUNK
+				if from >= uint64(size) {
+					return
+				}
+				to := uint32(ow.Min(int64(from+CHUNK), int64(size)))
+				f(uint32(from), to)
+			}
+		}()
+	}
+	waitGroup.Wait()
+}
+
+// build the same-level move graph of the current level [low, high]
+func newGraph(goroutines int, size uint32) *graph {
+	if int64(size)*mech.MOVE_CAP >= int64(^uint32(0)) {
+		ow.Panic("too many edges for 32-bit offsets:", size)
+	}
+
+	g := new(graph)
+	g.size = size
+	g.outDegree = make([]uint8, size)
+	g.successors = make([]uint32, int64(size)*int64(mech.MOVE_CAP))
+
+	// successors
+	parallel(goroutines, size, func(from, to uint32) {
+		for v := from; v < to; v++ {
+			legalMoves := mech.Unrank(rank(v)).LegalMoves()
+			for _, move := range legalMoves.Moves {
+				// only same-level
+				if legalMoves.Score[move] != 0 {
+					continue
+				}
+				g.successors[int64(v)*mech.MOVE_CAP+int64(g.outDegree[v])] = uint32(legalMoves.Next[move] - low)
+				g.outDegree[v]++
+			}
+		}
+	})
+
+	// predecessors: count, prefix sums, fill
+	g.offsets = make([]uint32, int64(size)+1)
+	for v := uint32(0); v < size; v++ {
+		for _, w := range g.next(v) {
+			g.offsets[w+1]++
+		}
+	}
+	for v := uint32(0); v < size; v++ {
+		g.offsets[v+1] += g.offsets[v]
+	}
+	g.predecs = make([]uint32, g.offsets[size])
+	fill := make([]uint32, size)
+	for v := uint32(0); v < size; v++ {
+		for _, w := range g.next(v) {
+			g.predecs[g.offsets[w]+fill[w]] = v
+			fill[w]++
+		}
+	}
+
+	ow.Log("vertices:", size, "edges:", len(g.predecs))
+	return g
+}
+
+// successors of v
+func (g *graph) next(v uint32) []uint32 {
+	from := int64(v) * mech.MOVE_CAP
+	return g.successors[from : from+int64(g.outDegree[v])]
+}
+
+// predecessors of v
+func (g *graph) previous(v uint32) []uint32 {
+	return g.predecs[g.offsets[v]:g.offsets[v+1]]
+}
+
+// Parallel SCC detection on a given level; returns the same SCCs as Tarjan(), in a different order
+func Parallel(level int8, goroutines int) (scc [][]int64) {
+	scc = make([][]int64, 0)
+
+	// range
+	ow.Log("level:", level, "goroutines:", goroutines)
+	low = ow.ZERO64
+	if level > 0 {
+		low = ow.LevelUpperLimits[level-1] + 1
+	}
+	high = ow.LevelUpperLimits[level]
+	ow.Log("from:", low, "to:", high)
+
+	if high-low+1 >= int64(^uint32(0)) {
+		ow.Panic("level:", level, "does not fit 32-bit local indices")
+	}
+	size := uint32(high - low + 1)
+	goroutines = ow.Max(goroutines, 1)
+
+	g := newGraph(goroutines, size)
+
+	// removed vertices: trimmed or assigned to an SCC
+	removed := make([]atomic.Bool, size)
+
+	colour := make([]uint32, size)
+	var mutex sync.Mutex
+	for round := 1; ; round++ {
+		// the SCCs found in the previous round may leave vertices without live neighbours
+		trim(goroutines, g, removed)
+
+		// fresh colours
+		var live atomic.Int64
+		parallel(goroutines, size, func(from, to uint32) {
+			for v := from; v < to; v++ {
+				if !removed[v].Load() {
+					colour[v] = v
+					live.Add(1)
+				}
+			}
+		})
+		ow.Log("round:", round, "live vertices:", live.Load())
+		if live.Load() == 0 {
+			break
+		}
+
+		// propagate the maximum colour forward
+		for changed := true; changed; {
+			var flag atomic.Bool
+			parallel(goroutines, size, func(from, to uint32) {
+				for v := from; v < to; v++ {
+					if removed[v].Load() {
+						continue
+					}
+					c := atomic.LoadUint32(&colour[v])
+					for _, w := range g.next(v) {
+						if removed[w].Load() {
+							continue
+						}
+						for {
+							old := atomic.LoadUint32(&colour[w])
+							if old >= c {
+								break
+							}
+							if atomic.CompareAndSwapUint32(&colour[w], old, c) {
+								flag.Store(true)
+								break
+							}
+						}
+					}
+				}
+			})
+			changed = flag.Load()
+		}
+
+		// backward search from each root, restricted to its colour
+		parallel(goroutines, size, func(from, to uint32) {
+			for root := from; root < to; root++ {
+				if colour[root] != root || removed[root].Load() {
+					continue
+				}
+				members := backward(g, root, colour, removed)
+				if len(members) > 1 {
+					mutex.Lock()
+					scc = append(scc, members)
+					mutex.Unlock()
+				}
+			}
+		})
+	}
+
+	return
+}
+
+// vertices of the root's colour that reach the root; these are removed from the graph.
+//
+// the vertices of one colour are visited by one goroutine only.
+func backward(g *graph, root uint32, colour []uint32, removed []atomic.Bool) []int64 {
+	members := make([]int64, 0)
+
+	removed[root].Store(true)
+	queue := []uint32{root}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		members = append(members, rank(v))
+		for _, w := range g.previous(v) {
+			if colour[w] == root && removed[w].CompareAndSwap(false, true) {
+				queue = append(queue, w)
+			}
+		}
+	}
+
+	ow.Log("root:", rank(root), "SCC size:", len(members))
+	return members
+}
+
+// remove vertices without live predecessors or successors, until stable
+func trim(goroutines int, g *graph, removed []atomic.Bool) {
+	trimmed := make([]bool, g.size)
+	for changed := true; changed; {
+		var count atomic.Int64
+		parallel(goroutines, g.size, func(from, to uint32) {
+			for v := from; v < to; v++ {
+				if removed[v].Load() {
+					continue
+				}
+				trimmed[v] = !alive(g.next(v), removed) || !alive(g.previous(v), removed)
+				if trimmed[v] {
+					count.Add(1)
+				}
+			}
+		})
+		parallel(goroutines, g.size, func(from, to uint32) {
+			for v := from; v < to; v++ {
+				if trimmed[v] {
+					removed[v].Store(true)
+				}
+			}
+		})
+		ow.Log("trimmed:", count.Load())
+		changed = count.Load() > 0
+	}
+}
+
+// is any of the vertices not removed?
+func alive(vertices []uint32, removed []atomic.Bool) bool {
+	for _, v := range vertices {
+		if !removed[v].Load() {
+			return true
+		}
+	}
+	return false
+}
+
+// run both Tarjan() and Parallel() and compare the SCCs found; panics on mismatch
+func Verify(level int8, goroutines int) [][]int64 {
+	sequential := NewCatalogue(level, Tarjan(level))
+	concurrent := NewCatalogue(level, Parallel(level, goroutines))
+
+	if len(sequential.Components) != len(concurrent.Components) {
+		ow.Panic("level:", level, "Tarjan:", len(sequential.Components), "SCCs, parallel:", len(concurrent.Components))
+	}
+	for i, component := range sequential.Components {
+		other := concurrent.Components[i]
+		if len(component.Members) != len(other.Members) {
+			ow.Panic("level:", level, "SCC:", component.ID, "Tarjan:", len(component.Members), "members, parallel:", len(other.Members))
+		}
+		for j, rank := range component.Members {
+			if other.Members[j] != rank {
+				ow.Panic("level:", level, "SCC:", component.ID, "Tarjan:", rank, "!= parallel:", other.Members[j])
+			}
+		}
+	}
+
+	ow.Log("level:", level, "verified:", len(concurrent.Components), "SCCs")
+	return concurrent.Lists()
+}
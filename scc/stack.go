@@ -6,7 +6,8 @@ import (
 	"sync"
 )
 
-// a thread-safe stack of 64-bit integers
+// a thread-safe stack of 64-bit integers;
+// Tarjan keeps its stack of nodes in one when it fits into memory, v. tarjanStack
 type Stack struct {
 	slice []int64
 	hash  map[int64]int64
@@ -106,3 +107,30 @@ func (stack *Stack) Pop() (int64, bool) {
 	stack.mutex.Unlock()
 	return pop, last >= 0
 }
+
+// about the bytes of RAM per element: slice and hash entry
+const STACK_BYTES = 64
+
+// Stack of 32-bit local indices, for Tarjan
+type memStack struct {
+	*Stack
+}
+
+func (stack memStack) Push(v uint32) {
+	stack.Stack.Push(int64(v))
+}
+
+func (stack memStack) Pop() uint32 {
+	v, ok := stack.Stack.Pop()
+	if !ok {
+		ow.Panic("pop from an empty stack")
+	}
+	return uint32(v)
+}
+
+func (stack memStack) Len() uint32 {
+	return uint32(stack.Size())
+}
+
+func (stack memStack) Close() {
+}
@@ -0,0 +1,23 @@
+package scc
+
+import (
+	"testing"
+)
+
+func TestStack(t *testing.T) {
+	stack := NewStack()
+	for _, element := range []int64{3, 1, 4} {
+		stack.Push(element)
+	}
+	if !stack.Member(1) || stack.Member(2) || stack.Size() != 3 || stack.First() != 3 || stack.Last() != 4 {
+		t.Errorf("stack: %v", stack)
+	}
+	for _, want := range []int64{4, 1, 3} {
+		if element, ok := stack.Pop(); !ok || element != want {
+			t.Errorf("Pop() = %d, %v; want %d, true", element, ok, want)
+		}
+	}
+	if _, ok := stack.Pop(); ok {
+		t.Error("Pop() of an empty stack")
+	}
+}
@@ -1,6 +1,6 @@
-// Find all members of strongly-connected components on a given level.
+// Find all strongly-connected components on a given level.
 //
-// Implements Tarjan's SCC algorithm, single-threaded (i.e., single-goroutined), in-memory.
+// Implements Tarjan's SCC algorithm, single-threaded (i.e., single-goroutined).
 //
 // The Oware same-level game space is a directed graph G(V, E).
 // Positions are the vertices V and moves without captures are the edges E.
@@ -13,38 +13,74 @@
 // This is, to our knowledge, the fastest algorithm known.
 // Kossajaru's is somewhat simpler, though somewhat (linear factor) slower.
 //
-// Both Tarjan and Kossajaru are linear-time and linear-space in-memory algorithms.
-// We have no knowledge of a useable parallelization.
-// For this reason, cycles can only be computed for levels with few stones.
-// E.g., it overflows memory at level-14 on an 8GB-RAM computer.
+// The depth-first search is iterative: an explicit call stack of frames replaces the recursion.
+// Deep same-level move chains therefore do not grow the goroutine stack.
+//
+// Both Tarjan and Kossajaru are linear-time and linear-space algorithms.
+// Neither parallelizes: v. Parallel() for a multi-goroutine alternative that finds the same members.
+//
+// # MEMORY
+//
+// Vertices are addressed by their 32-bit local index within the level (rank - lowest rank).
+// Per vertex we keep:
+//   - two bits: visited and on-stack membership, packed in bit maps, always in memory
+//   - the 32-bit index and lowLink
+//   - 8 bytes on the call stack (node and successor cursor), while the vertex is there
+//   - a place on the Tarjan stack, while the vertex is there: a Stack entry, about STACK_BYTES, or 4 bytes in an array
+//
+// The Tarjan stack is a Stack if it fits into MemoryLimit along with the rest, otherwise an array.
+// The arrays and the stacks in arrays are spilled to disk when, together with the bit maps, they do not fit into MemoryLimit.
+// Their page caches then share the rest of MemoryLimit, but take at least 64 KB each.
+//
+// Levels up to 31 fit into 32-bit local indices.
 package scc
 
 import (
-	"math/big"
 	"sankofa/mech"
 	"sankofa/ow"
 )
 
 // index: the traversal order
-var index []int64
+var index array
 
 // lowLink: lowest index reachable in the subtree of given node
-var lowLink []int64
+var lowLink array
+
+// the Tarjan stack of nodes: a Stack in memory, an arrayStack when spilled
+type tarjanStack interface {
+	Push(v uint32)
+	Pop() uint32
+	Len() uint32
+	Close()
+}
 
 // stack of nodes
-var stack *Stack
+var stack tarjanStack
+
+// call stack: the nodes of the suspended strongConnect() calls and the number of successors visited so far
+var calls, cursors arrayStack
 
 // membership bit maps
-var stackMember, indexMember *big.Int
+var onStack, visited Bitset
 
 // range
 var high, low int64
 
 // current index
-var count int64
+var count uint32
+
+// number of call frames that keep their successors cached
+const RING = 4096
+
+// successors of the call frames at the top of the call stack
+var ring [RING]struct {
+	depth int
+	next  []uint32
+}
 
-func Tarjan(level int8) (scc []int64) {
-	scc = make([]int64, 0)
+// the SCCs of a level; one-man SCCs are omitted
+func Tarjan(level int8) (scc [][]int64) {
+	scc = make([][]int64, 0)
 
 	// initialize globals
 	// range
@@ -56,97 +92,180 @@ func Tarjan(level int8) (scc []int64) {
 	high = ow.LevelUpperLimits[level]
 	ow.Log("from:", low, "to:", high)
 
-	index = make([]int64, high-low+1)
-	lowLink = make([]int64, high-low+1)
-	stack = NewStack()
-	stackMember = big.NewInt(0)
-	indexMember = big.NewInt(0)
-
-	for rank := low; rank <= high; rank++ {
-		// WARNING Tarjan only works on 64-bit architectures: indices would overflow an int32
-		if indexMember.Bit(int(rank-low)) == 0 {
-			ow.Log("⇢enter with:", rank)
-			scc = append(scc, strongConnect(rank)...)
+	if high-low+1 >= int64(^uint32(0)) {
+		ow.Panic("level:", level, "does not fit 32-bit local indices")
+	}
+	size := uint32(high - low + 1)
+
+	// the bit maps stay in memory; index, lowLink and the stacks share the rest of the budget
+	onStack = NewBitset(size)
+	visited = NewBitset(size)
+	reserved := onStack.Bytes() + visited.Bytes()
+	index = newArray(size, 5, reserved)
+	lowLink = newArray(size, 5, reserved)
+	if int64(size)*(4*4+STACK_BYTES)+reserved <= MemoryLimit {
+		stack = memStack{NewStack()}
+	} else {
+		stack = &arrayStack{array: newArray(size, 5, reserved)}
+	}
+	calls = arrayStack{array: newArray(size, 5, reserved)}
+	cursors = arrayStack{array: newArray(size, 5, reserved)}
+	defer index.Close()
+	defer lowLink.Close()
+	defer stack.Close()
+	defer calls.Close()
+	defer cursors.Close()
+	count = 0
+	for i := range ring {
+		ring[i].depth = -1
+	}
+
+	for v := uint32(0); v < size; v++ {
+		if !visited.Bit(v) {
+			ow.Log("⇢enter with:", rank(v))
+			scc = append(scc, strongConnect(v)...)
 		} else {
-			ow.Log("⇠skip/seen:", rank)
+			ow.Log("⇠skip/seen:", rank(v))
 		}
 	}
 
-	ow.Log("remaining stack size:", stack.Size())
-	for pop, ok := stack.Pop(); ok; pop, ok = stack.Pop() {
-		ow.Log("POP: leftover:", pop, "index:", index[pop-low], "lowLink:", lowLink[pop-low], "stack size:", stack.Size())
-	}
+	ow.Log("remaining stack size:", stack.Len())
 
 	return
 }
 
+// local index ⇢ rank
+func rank(v uint32) int64 {
+	return low + int64(v)
+}
+
+// same-level successors of a node: (v, w) are the edges of the directed graph;
+// cached for the frames at the top of the call stack
+func successors(depth int, v uint32) []uint32 {
+	cache := &ring[depth%RING]
+	if cache.depth == depth {
+		return cache.next
+	}
+
+	legalMoves := mech.Unrank(rank(v)).LegalMoves()
+	next := make([]uint32, 0, len(legalMoves.Moves))
+	for _, move := range legalMoves.Moves {
+		// only same-level
+		if legalMoves.Score[move] != 0 {
+			continue
+		}
+		next = append(next, uint32(legalMoves.Next[move]-low))
+	}
+
+	cache.depth = depth
+	cache.next = next
+	return next
+}
+
+// initialize book keeping for a newly discovered node, place it on the stack and call it
+func discover(v uint32) {
+	index.Set(v, count)
+	lowLink.Set(v, count)
+	visited.Set(v)
+	count = count + 1
+
+	// place on stack
+	stack.Push(v)
+	onStack.Set(v)
+	ow.Log("PUSH:", rank(v), "index:", index.Get(v), "stack size:", stack.Len())
+
+	// the call frame
+	calls.Push(v)
+	cursors.Push(0)
+}
+
+// lower the lowLink of v to at most x
+func lower(v, x uint32) {
+	if x < lowLink.Get(v) {
+		lowLink.Set(v, x)
+	}
+}
+
 // Find strongly connected components in a directed graph.
 // Oware positions are the nodes, legal moves at the same level are the vertices.
 // The procedure is called for each node (as a potential starting point).
 // Already seen nodes are then skipped.
-// The minor split score is saved for all SCC members that are not already set.
-func strongConnect(rank int64) []int64 {
-	scc := make([]int64, 0)
+// Returns all SCCs found in the depth-first tree rooted at v.
+//
+// Each frame on the call stack stands for a suspended recursive call;
+// a frame is resumed when the subtree of its current successor is done.
+func strongConnect(v uint32) [][]int64 {
+	scc := make([][]int64, 0)
 
-	// initialize book keeping
-	index[rank-low] = count
-	// WARNING Tarjan only works on 64-bit architectures: indices would overflow an int32
-	indexMember.SetBit(indexMember, int(rank-low), 1)
-	lowLink[rank-low] = count
-	count = count + 1
+	ring[0].depth = -1
+	discover(v)
+	for calls.Len() > 0 {
+		depth := int(calls.Len()) - 1
+		node, cursor := calls.Peek(0), cursors.Peek(0)
 
-	// place on stack
-	stack.Push(rank)
-	stackMember.SetBit(stackMember, int(rank-low), 1)
-	ow.Log("PUSH:", rank, "index:", index[rank-low], "stack size:", stack.Size())
+		// successors
+		next := successors(depth, node)
+		if int(cursor) < len(next) {
+			w := next[cursor]
+			cursors.Set(uint32(depth), cursor+1)
 
-	// successors
-	legalMoves := mech.Unrank(rank).LegalMoves()
-	for move := range legalMoves.Next {
-		// only same-level
-		if legalMoves.Score[move] != 0 {
+			if !visited.Bit(w) {
+				ow.Log("descend to:", rank(w))
+				ring[(depth+1)%RING].depth = -1
+				discover(w)
+			} else if onStack.Bit(w) {
+				lower(node, index.Get(w))
+			}
 			continue
 		}
 
-		// (rank, next) is an edge of the directed graph.
-		next := legalMoves.Next[move]
-		// WARNING Tarjan only works on 64-bit architectures: indices would overflow an int32
-		if indexMember.Bit(int(next-low)) == 0 {
-			ow.Log("recurse on:", next)
-			strongConnect(next)
-			lowLink[rank-low] = ow.Min(lowLink[rank-low], lowLink[next-low])
-		} else if stackMember.Bit(int(next-low)) == 1 {
-			// WARNING Tarjan only works on 64-bit architectures: indices would overflow an int32
-			lowLink[rank-low] = ow.Min(lowLink[rank-low], index[next-low])
+		// all successors done: return from the call
+		calls.Pop()
+		cursors.Pop()
+		if members := component(node); len(members) > 0 {
+			scc = append(scc, members)
+		}
+
+		// propagate the lowLink to the caller
+		if depth > 0 {
+			lower(calls.Peek(0), lowLink.Get(node))
 		}
 	}
 
-	// dump strongly connected component if this is a root node
-	if lowLink[rank-low] == index[rank-low] {
-		ow.Log("root:", rank, "index:", index[rank-low], "lowLink:", lowLink[rank-low])
-		// count elements strongly connected component
-		var size int64
-		for pop, ok := stack.Pop(); ok; pop, ok = stack.Pop() {
-			size = size + 1
-			// WARNING Tarjan only works on 64-bit architectures: indices would overflow an int32
-			stackMember.SetBit(stackMember, int(rank-low), 0)
-			ow.Log("POP:", pop, "index:", index[pop-low], "stack size:", stack.Size())
-			// until reached root
-			if pop == rank {
-				// one-man SCCs don't count
-				if size == 1 {
-					ow.Log("free:", rank, "index:", index[rank-low], "lowLink:", lowLink[rank-low])
-				} else {
-					ow.Log("SCC root: ", rank, "index:", index[rank-low], "lowLink:", lowLink[rank-low])
-					// output
-					scc = append(scc, pop)
-				}
-				break
+	return scc
+}
+
+// dump strongly connected component if this is a root node
+func component(v uint32) []int64 {
+	scc := make([]int64, 0)
+
+	if lowLink.Get(v) != index.Get(v) {
+		return scc
+	}
+
+	ow.Log("root:", rank(v), "index:", index.Get(v), "lowLink:", lowLink.Get(v))
+	// count elements strongly connected component
+	var size int64
+	for stack.Len() > 0 {
+		pop := stack.Pop()
+		size = size + 1
+		onStack.Clear(pop)
+		ow.Log("POP:", rank(pop), "index:", index.Get(pop), "stack size:", stack.Len())
+		// until reached root
+		if pop == v {
+			// one-man SCCs don't count
+			if size == 1 {
+				ow.Log("free:", rank(v), "index:", index.Get(v), "lowLink:", lowLink.Get(v))
 			} else {
-				ow.Log("SCC element: ", pop, "index:", index[pop-low], "lowLink:", lowLink[pop-low])
+				ow.Log("SCC root: ", rank(v), "index:", index.Get(v), "lowLink:", lowLink.Get(v))
 				// output
-				scc = append(scc, pop)
+				scc = append(scc, rank(pop))
 			}
+			break
+		} else {
+			ow.Log("SCC element: ", rank(pop), "index:", index.Get(pop), "lowLink:", lowLink.Get(pop))
+			// output
+			scc = append(scc, rank(pop))
 		}
 	}
 
@@ -0,0 +1,98 @@
+package scc
+
+import (
+	"os"
+	"reflect"
+	"sankofa/mech"
+	"sankofa/ow"
+	"slices"
+	"testing"
+)
+
+// quiet: the logs cost more than the searches
+func TestMain(m *testing.M) {
+	ow.Verbose = false
+	os.Exit(m.Run())
+}
+
+// the levels searched by the tests; each takes a fraction of a second
+const TEST_LEVELS = 6
+
+// the ranks of a level that lie on a cycle of same-level moves, by brute force
+func onCycle(level int8) []int64 {
+	low := ow.ZERO64
+	if level > 0 {
+		low = ow.LevelUpperLimits[level-1] + 1
+	}
+	high := ow.LevelUpperLimits[level]
+
+	next := make([][]int64, high-low+1)
+	for rank := low; rank <= high; rank++ {
+		legalMoves := mech.Unrank(rank).LegalMoves()
+		for _, move := range legalMoves.Moves {
+			if legalMoves.Score[move] == 0 {
+				next[rank-low] = append(next[rank-low], legalMoves.Next[move])
+			}
+		}
+	}
+
+	members := []int64{}
+	for rank := low; rank <= high; rank++ {
+		seen := make([]bool, high-low+1)
+		queue := slices.Clone(next[rank-low])
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			if v == rank {
+				members = append(members, rank)
+				break
+			}
+			if !seen[v-low] {
+				seen[v-low] = true
+				queue = append(queue, next[v-low]...)
+			}
+		}
+	}
+	return members
+}
+
+// Tarjan() finds exactly the positions on same-level cycles
+func TestTarjan(t *testing.T) {
+	// the brute force is quadratic: one level less
+	for level := int8(1); level < TEST_LEVELS; level++ {
+		members := NewCatalogue(level, Tarjan(level)).Members()
+		slices.Sort(members)
+		if want := onCycle(level); !slices.Equal(members, want) {
+			t.Errorf("level %d: Tarjan: %d members, brute force: %d", level, len(members), len(want))
+		}
+	}
+}
+
+// the arrays spilled to disk give the same SCCs as in memory
+func TestTarjanSpilled(t *testing.T) {
+	defer func(limit int64, dir string) { MemoryLimit, TempDir = limit, dir }(MemoryLimit, TempDir)
+	TempDir = t.TempDir()
+
+	for level := int8(1); level <= TEST_LEVELS; level++ {
+		MemoryLimit = int64(4) << 30
+		inMemory := NewCatalogue(level, Tarjan(level)).Lists()
+		MemoryLimit = 0
+		spilled := NewCatalogue(level, Tarjan(level)).Lists()
+		if !reflect.DeepEqual(inMemory, spilled) {
+			t.Errorf("level %d: in memory: %d SCCs, spilled: %d SCCs", level, len(inMemory), len(spilled))
+		}
+	}
+}
+
+// Parallel() finds the SCCs of Tarjan(); run with -race
+func TestParallel(t *testing.T) {
+	for level := int8(1); level <= TEST_LEVELS; level++ {
+		sequential := NewCatalogue(level, Tarjan(level)).Lists()
+		for _, goroutines := range []int{1, 4} {
+			concurrent := NewCatalogue(level, Parallel(level, goroutines)).Lists()
+			if !reflect.DeepEqual(sequential, concurrent) {
+				t.Errorf("level %d, %d goroutines: Tarjan: %d SCCs, parallel: %d SCCs", level, goroutines, len(sequential), len(concurrent))
+			}
+		}
+	}
+}